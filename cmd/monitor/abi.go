@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+)
+
+// monitor abi check [-config file] | monitor abi diff <old.abi> <new.abi>
+func abiCommand(args []string) error {
+	flags := flag.NewFlagSet("abi", flag.ExitOnError)
+	configFile := flags.String("config", "", "check: config file with the ABI files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor abi check [flags]\n       monitor abi diff <old.abi> <new.abi>")
+		flags.PrintDefaults()
+	}
+
+	action, args := commandAction(args)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var issues []*monitor.AbiIssue
+	switch action {
+	case "check":
+		config, err := monitor.ReadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		issues = monitor.CheckAbi(config)
+	case "diff":
+		if flags.NArg() != 2 {
+			flags.Usage()
+			return errors.New("diff requires the old and the new ABI files")
+		}
+		var err error
+		if issues, err = monitor.DiffAbi(flags.Arg(0), flags.Arg(1)); err != nil {
+			return err
+		}
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	breaking := 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Breaking {
+			breaking++
+		}
+	}
+
+	if breaking > 0 {
+		return fmt.Errorf("%d breaking issues", breaking)
+	}
+	fmt.Println("ok")
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/DaoCasino/platform-action-monitor/pkg/bench"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// monitor bench -url ws://localhost:8888/ -token <token> -sessions 100 -mixes "event_0,event_1;casino.game_finished" [-offsets 0,123] [-duration 1m] [-rampup 10s]
+func benchCommand(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	url := flags.String("url", "ws://localhost:8888/", "monitor websocket url")
+	token := flags.String("token", "", "token of the shared database")
+	sessions := flags.Int("sessions", 10, "number of websocket sessions")
+	mixes := flags.String("mixes", "", "topic mixes separated by ';', the sessions take the mixes in turn, eg event_0,event_1;casino.game_finished")
+	offsets := flags.String("offsets", "", "comma separated offsets, the sessions take the offsets in turn, 0 by default")
+	duration := flags.Duration("duration", time.Minute, "total time of the benchmark")
+	rampUp := flags.Duration("rampup", 0, "time to open the sessions")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor bench [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *mixes == "" {
+		flags.Usage()
+		return errors.New("mixes are required")
+	}
+
+	config := bench.Config{
+		URL:      *url,
+		Token:    *token,
+		Sessions: *sessions,
+		Duration: *duration,
+		RampUp:   *rampUp,
+	}
+
+	for _, mix := range strings.Split(*mixes, ";") {
+		topics := make([]string, 0)
+		for _, topic := range strings.Split(mix, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				topics = append(topics, monitor.ResolveTopic(topic))
+			}
+		}
+		config.Mixes = append(config.Mixes, topics)
+	}
+
+	if *offsets != "" {
+		for _, value := range strings.Split(*offsets, ",") {
+			offset, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid offset %q: %s", value, err)
+			}
+			config.Offsets = append(config.Offsets, offset)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	report, err := bench.Run(ctx, config, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("sessions    %d (failed %d, dropped %d)\n", report.Sessions, report.Failed, report.Dropped)
+	fmt.Printf("events      %d (live %d)\n", report.Events, report.Live)
+	fmt.Printf("duration    %s\n", report.Duration.Round(time.Millisecond))
+	fmt.Printf("throughput  %.1f events/s\n", report.Throughput)
+	fmt.Printf("latency     p50 %s  p90 %s  p99 %s  max %s\n",
+		report.Latency.P50.Round(time.Millisecond),
+		report.Latency.P90.Round(time.Millisecond),
+		report.Latency.P99.Round(time.Millisecond),
+		report.Latency.Max.Round(time.Millisecond),
+	)
+	for _, message := range report.Errors {
+		fmt.Fprintln(os.Stderr, "error:", message)
+	}
+
+	if report.Failed != 0 || report.Dropped != 0 {
+		return fmt.Errorf("%d sessions failed, %d dropped", report.Failed, report.Dropped)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"os"
+	"strings"
+)
+
+// monitor decode [-config file] [-source name] <hex act_data> | -offset <offset>
+func decodeCommand(args []string) error {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	configFile := flags.String("config", "", "config file")
+	offset := flags.Uint64("offset", 0, "fetch act_data of the action with the offset (receipt_global_sequence) from the database")
+	source := flags.String("source", "", "source decoding the hex act_data, the source of the action with -offset")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor decode [flags] <hex act_data>\n       monitor decode [flags] -offset <offset>")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// the offset 0 is valid, the flag is set if it is on the command line
+	offsetSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "offset" {
+			offsetSet = true
+		}
+	})
+
+	var actData []byte
+	switch {
+	case !offsetSet && flags.NArg() == 1:
+		var err error
+		if actData, err = hex.DecodeString(strings.TrimPrefix(flags.Arg(0), "0x")); err != nil {
+			return fmt.Errorf("invalid hex act_data: %s", err)
+		}
+	case offsetSet && flags.NArg() == 0:
+	default:
+		flags.Usage()
+		return errors.New("hex act_data or -offset is required")
+	}
+
+	config, err := monitor.ReadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	decoder, err := monitor.NewAbiDecoder(config, nil)
+	if err != nil {
+		return err
+	}
+
+	var result *monitor.DecodeResult
+	if actData != nil {
+		result, err = decoder.DecodeActData(*source, actData)
+	} else {
+		ctx := context.Background()
+		pool, connectErr := pgxpool.Connect(ctx, config.DatabaseURL())
+		if connectErr != nil {
+			return connectErr
+		}
+		defer pool.Close()
+
+		result, err = monitor.DecodeOffset(ctx, pool, config, decoder, *offset)
+	}
+
+	// the decoded part is printed on error too
+	if result != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(result); encodeErr != nil {
+			return encodeErr
+		}
+	}
+	return err
+}
@@ -1,8 +1,16 @@
 #!/bin/sh
 set -e
 
-dbmate up
-
-dbmate -e SHARED_DATABASE_URL -d /app/shared-db/migrations up
+# the deployments made for dbmate set DATABASE_URL and SHARED_DATABASE_URL,
+# the migrations are applied to them like by dbmate
+(
+  if [ -n "$DATABASE_URL" ]; then
+    export MONITOR_DATABASE_URL="$DATABASE_URL"
+  fi
+  if [ -n "$SHARED_DATABASE_URL" ]; then
+    export MONITOR_SHAREDDATABASE_URL="$SHARED_DATABASE_URL"
+  fi
+  exec ./monitor migrate up -config /app/configs/config.yml
+)
 
 ./monitor -config /app/configs/config.yml
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// monitor export [-config file] [-format json|csv] [-output file] [-from-offset N] [-to-offset N] [-from time] [-to time] [-topics a,b]
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	configFile := flags.String("config", "", "config file")
+	format := flags.String("format", monitor.ExportFormatJSON, "output format: json lines or csv")
+	output := flags.String("output", "", "output file, stdout by default")
+	fromOffset := flags.Uint64("from-offset", 0, "offset of the first action")
+	toOffset := flags.Uint64("to-offset", 0, "offset of the last action, inclusive")
+	from := flags.String("from", "", "block time of the first action, RFC3339")
+	to := flags.String("to", "", "block time after the last action, RFC3339")
+	topics := flags.String("topics", "", "comma separated topics, eg event_4,casino.game_finished, all events by default")
+	pageSize := flags.Uint("page", 1000, "actions fetched by one query")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor export [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	request := &monitor.ExportRequest{
+		FromOffset: *fromOffset,
+		ToOffset:   *toOffset,
+		Format:     *format,
+		PageSize:   *pageSize,
+	}
+
+	var err error
+	if *from != "" {
+		if request.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("from: %s", err)
+		}
+	}
+	if *to != "" {
+		if request.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("to: %s", err)
+		}
+	}
+	if *topics != "" {
+		request.Topics = strings.Split(*topics, ",")
+	}
+
+	config, err := monitor.ReadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	decoder, err := monitor.NewAbiDecoder(config, nil)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	buffer := bufio.NewWriter(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	pool, err := pgxpool.Connect(ctx, config.DatabaseURL())
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	stats, err := monitor.Export(ctx, pool, config, decoder, buffer, request)
+	if flushErr := buffer.Flush(); err == nil {
+		err = flushErr
+	}
+
+	// resume with -from-offset <offset>
+	fmt.Fprintf(os.Stderr, "exported %d events, skipped %d actions, next offset %d\n", stats.Events, stats.Skipped, stats.NextOffset)
+	return err
+}
@@ -8,20 +8,53 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
 
 const withTimeout = 5 * time.Second
 
+// subcommands, without a subcommand the monitor serves websocket connections
+var commands = map[string]func(args []string) error{
+	"migrate": migrateCommand,
+	"token":   tokenCommand,
+	"decode":  decodeCommand,
+	"abi":     abiCommand,
+	"tail":    tailCommand,
+	"export":  exportCommand,
+	"bench":   benchCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatalf("%s: %s", os.Args[1], err.Error())
+			}
+			return
+		}
+	}
+
+	serve()
+}
+
+// commandAction splits the action from the flags, eg monitor migrate up -config file
+func commandAction(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+func serve() {
 	configFile := flag.String("config", "", "config file")
 	flag.Parse()
 
 	parentContext := context.Background()
 	mainContext, mainCancel := context.WithCancel(parentContext)
 
-	server, monitorCancelFunc, err := monitor.Init(configFile, mainContext)
+	server, monitorShutdown, err := monitor.Init(configFile, mainContext)
 	if err != nil {
 		log.Fatalf("monitor init: %s", err.Error())
 	}
@@ -38,19 +71,21 @@ func main() {
 	log.Printf("server is listening on %s", server.Addr)
 	<-done
 	log.Println("done signal")
-	mainCancel()
 
 	shutdownContextWithTimeout, cancelWaitShutdown := context.WithTimeout(parentContext, withTimeout)
 
 	defer func() {
-		// TODO: close all connections here
-		monitorCancelFunc()
-
 		cancelWaitShutdown()
+		mainCancel()
 		log.Println("connection closed")
 	}()
 
+	// stop accepting connections, websocket connections are hijacked and closed by the monitor
 	if err := server.Shutdown(shutdownContextWithTimeout); err != nil {
-		log.Fatalf("server shutdown failed: %s", err.Error())
+		log.Printf("server shutdown failed: %s", err.Error())
+	}
+
+	if err := monitorShutdown(shutdownContextWithTimeout); err != nil {
+		log.Printf("monitor shutdown: %s", err.Error())
 	}
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/db"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/DaoCasino/platform-action-monitor/pkg/migrate"
+	"github.com/DaoCasino/platform-action-monitor/shared-db"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+const (
+	databaseChain  = "chain"
+	databaseShared = "shared"
+	databaseAll    = "all"
+)
+
+type migrationSet struct {
+	database   string
+	url        string
+	migrations fs.FS
+}
+
+// monitor migrate up|down|status [-config file] [-database chain|shared|all]
+func migrateCommand(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configFile := flags.String("config", "", "config file")
+	database := flags.String("database", databaseAll, "database to migrate: chain, shared or all, down requires chain or shared")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor migrate up|down|status [flags]")
+		flags.PrintDefaults()
+	}
+
+	action, args := commandAction(args)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if action == "" {
+		action = flags.Arg(0)
+	}
+
+	switch action {
+	case "up", "status":
+	case "down":
+		if *database == databaseAll {
+			return errors.New("down requires -database chain or shared")
+		}
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	config, err := monitor.ReadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	sets := make([]*migrationSet, 0, 2)
+	if *database == databaseChain || *database == databaseAll {
+		sets = append(sets, &migrationSet{databaseChain, config.DatabaseURL(), db.Migrations})
+	}
+	if *database == databaseShared || *database == databaseAll {
+		sets = append(sets, &migrationSet{databaseShared, config.SharedDatabaseURL(), shareddb.Migrations})
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("unknown database %s", *database)
+	}
+
+	ctx := context.Background()
+	for _, set := range sets {
+		if err := set.run(ctx, action); err != nil {
+			return fmt.Errorf("%s database: %s", set.database, err)
+		}
+	}
+	return nil
+}
+
+func (s *migrationSet) run(ctx context.Context, action string) error {
+	migrations, err := migrate.Load(s.migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	pool, err := pgxpool.Connect(ctx, s.url)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrator := migrate.NewMigrator(pool, migrations)
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			log.Printf("%s: applied %s_%s", s.database, migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Printf("%s: no pending migrations", s.database)
+		}
+		return err
+	case "down":
+		migration, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			log.Printf("%s: no applied migrations", s.database)
+		} else {
+			log.Printf("%s: rolled back %s_%s", s.database, migration.Version, migration.Name)
+		}
+		return nil
+	default:
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, migration := range status {
+			state := "pending"
+			if migration.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.database, migration.Version, migration.Name, state)
+		}
+		return w.Flush()
+	}
+}
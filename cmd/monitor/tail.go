@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/DaoCasino/platform-action-monitor/pkg/client"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	tailFormatJSON  = "json"
+	tailFormatTable = "table"
+
+	tailTableRow = "%-12s  %-20s  %-10s  %-24s  %10s  %10s  %8s  %-12s  %s\n"
+)
+
+// monitor tail -url ws://localhost:8888/ -token <token> -topics game_finished,casino.event_0 [-offset N] [-casino id] [-game id] [-format json|table]
+func tailCommand(args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	url := flags.String("url", "ws://localhost:8888/", "monitor websocket url")
+	token := flags.String("token", "", "token of the shared database")
+	topics := flags.String("topics", "", "comma separated topics, eg event_4,casino.game_finished,alert_stuck_game")
+	offset := flags.Uint64("offset", 0, "offset of the first event")
+	casinoID := flags.Uint64("casino", 0, "print the events of the casino only")
+	gameID := flags.Uint64("game", 0, "print the events of the game only")
+	format := flags.String("format", tailFormatJSON, "output format: json lines or table")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor tail [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *token == "" || *topics == "" {
+		flags.Usage()
+		return errors.New("token and topics are required")
+	}
+	if *format != tailFormatJSON && *format != tailFormatTable {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	filters := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		filters[f.Name] = true
+	})
+
+	names := strings.Split(*topics, ",")
+	for i, name := range names {
+		names[i] = monitor.ResolveTopic(strings.TrimSpace(name))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	c := client.New(client.Config{URL: *url, Token: *token, Topics: names, Offset: *offset}, nil)
+	result := make(chan error, 1)
+	go func() {
+		result <- c.Run(ctx)
+	}()
+
+	if *format == tailFormatTable {
+		fmt.Printf(tailTableRow, "OFFSET", "BLOCK TIME", "SOURCE", "EVENT", "CASINO", "GAME", "REQ", "SENDER", "DATA")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for event := range c.Events() {
+		if (filters["casino"] && event.CasinoID != *casinoID) || (filters["game"] && event.GameID != *gameID) {
+			continue
+		}
+
+		if *format == tailFormatJSON {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Printf(tailTableRow,
+			fmt.Sprint(event.Offset),
+			event.BlockTime.UTC().Format(time.RFC3339),
+			event.Source,
+			monitor.EventTypeName(event.EventType),
+			fmt.Sprint(event.CasinoID),
+			fmt.Sprint(event.GameID),
+			fmt.Sprint(event.RequestID),
+			event.Sender,
+			string(event.Data),
+		)
+	}
+
+	// resume with -offset <offset>
+	fmt.Fprintf(os.Stderr, "next offset %d\n", c.Offset())
+	return <-result
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// monitor token create|list|revoke [-config file] [-label text] [-expires duration] [-scopes topics] [id]
+func tokenCommand(args []string) error {
+	flags := flag.NewFlagSet("token", flag.ExitOnError)
+	configFile := flags.String("config", "", "config file")
+	label := flags.String("label", "", "create: token description")
+	expires := flags.Duration("expires", 0, "create: token lifetime, eg 720h, no expiry if zero")
+	scopes := flags.String("scopes", "", "create: comma separated topics allowed to subscribe, eg event_0,casino.*, all topics if empty")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monitor token create|list|revoke [flags] [id]")
+		flags.PrintDefaults()
+	}
+
+	action, args := commandAction(args)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if action == "" && len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "create", "list":
+	case "revoke":
+		if len(args) != 1 {
+			flags.Usage()
+			return errors.New("revoke requires the token id")
+		}
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	config, err := monitor.ReadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, config.SharedDatabaseURL())
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	switch action {
+	case "create":
+		var expiresAt *time.Time
+		if *expires != 0 {
+			at := time.Now().Add(*expires)
+			expiresAt = &at
+		}
+
+		var scopeList []string
+		if *scopes != "" {
+			scopeList = strings.Split(*scopes, ",")
+		}
+
+		token, user, err := monitor.CreateToken(ctx, pool, *label, expiresAt, scopeList)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "created token id %d, the token is not stored and shown only once\n", user.ID)
+		fmt.Println(token)
+		return nil
+	case "revoke":
+		ID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid id: %s", err)
+		}
+		if err := monitor.RevokeToken(ctx, pool, ID); err == pgx.ErrNoRows {
+			return fmt.Errorf("token id %d not found or already revoked", ID)
+		} else if err != nil {
+			return err
+		}
+		fmt.Printf("revoked token id %d\n", ID)
+		return nil
+	default:
+		users, err := monitor.ListTokens(ctx, pool)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tLABEL\tSCOPES\tCREATED\tEXPIRES\tSTATUS")
+		now := time.Now()
+		for _, user := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", user.ID, user.Label, strings.Join(user.Scopes, ","),
+				user.CreatedAt.Format(time.RFC3339), formatTime(user.ExpiresAt), user.Status(now))
+		}
+		return w.Flush()
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
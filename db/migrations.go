@@ -0,0 +1,7 @@
+// Package db embeds the migrations of the chain database
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
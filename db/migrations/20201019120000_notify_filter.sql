@@ -0,0 +1,55 @@
+-- migrate:up
+-- the monitor writes the filters of its sources for its channel on listen and deletes them on stop, null matches any value
+CREATE TABLE chain.action_trace_notify_filter
+(
+    channel     varchar(63) NOT NULL,
+    act_account varchar(13),
+    act_name    varchar(13)
+);
+
+CREATE INDEX action_trace_notify_filter_index_channel ON chain.action_trace_notify_filter USING btree (channel);
+
+-- the actions matching the filters are notified to the channels of the filters with the JSON payload,
+-- the other actions to new_action_trace with the offset payload of the monitors without the notify filter
+CREATE OR REPLACE FUNCTION chain.action_trace_notify_trigger() RETURNS trigger AS
+$$
+DECLARE
+    payload text;
+    target  text;
+    claimed boolean := false;
+BEGIN
+    payload := json_build_object(
+        'offset', NEW.receipt_global_sequence,
+        'act_account', NEW.act_account,
+        'act_name', NEW.act_name
+    )::text;
+
+    FOR target IN
+        SELECT DISTINCT filter.channel
+        FROM chain.action_trace_notify_filter filter
+        WHERE (filter.act_account IS NULL OR filter.act_account = NEW.act_account)
+          AND (filter.act_name IS NULL OR filter.act_name = NEW.act_name)
+        LOOP
+            PERFORM pg_notify(target, payload);
+            claimed := true;
+        END LOOP;
+
+    IF NOT claimed THEN
+        PERFORM pg_notify('new_action_trace', NEW.receipt_global_sequence::text);
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- migrate:down
+CREATE OR REPLACE FUNCTION chain.action_trace_notify_trigger() RETURNS trigger AS
+$$
+DECLARE
+BEGIN
+    PERFORM pg_notify('new_action_trace', NEW.receipt_global_sequence::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX chain.action_trace_notify_filter_index_channel;
+DROP TABLE chain.action_trace_notify_filter;
@@ -1,6 +1,6 @@
 module github.com/DaoCasino/platform-action-monitor
 
-go 1.12
+go 1.16
 
 require (
 	github.com/eoscanada/eos-go v0.9.1-0.20200401171810-21f9a1430901
@@ -12,6 +12,7 @@ require (
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/lucsky/cuid v1.0.2
 	github.com/prometheus/client_golang v0.9.1
+	github.com/prometheus/client_model v0.0.0-20180712105110-5c3871d89910
 	github.com/stretchr/testify v1.5.1
 	github.com/tevino/abool v0.0.0-20170917061928-9b9efcf221b5
 	go.uber.org/zap v1.14.0
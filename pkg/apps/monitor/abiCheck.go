@@ -0,0 +1,291 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AbiIssue is a problem of the ABI file found by CheckAbi or DiffAbi
+type AbiIssue struct {
+	File string
+	// breaking issues fail the decoding, eg of the events stored with the old ABI
+	Breaking bool
+	Message  string
+}
+
+func (i *AbiIssue) String() string {
+	level := "warning"
+	if i.Breaking {
+		level = "error"
+	}
+	return fmt.Sprintf("%s: %s: %s", level, i.File, i.Message)
+}
+
+type abiIssues []*AbiIssue
+
+func (i *abiIssues) breaking(file string, format string, args ...interface{}) {
+	*i = append(*i, &AbiIssue{file, true, fmt.Sprintf(format, args...)})
+}
+
+func (i *abiIssues) warning(file string, format string, args ...interface{}) {
+	*i = append(*i, &AbiIssue{file, false, fmt.Sprintf(format, args...)})
+}
+
+// types decoded by eos-go without the ABI definitions
+var abiBuiltinTypes = map[string]bool{
+	"bool": true, "int8": true, "uint8": true, "int16": true, "uint16": true, "int32": true, "uint32": true,
+	"int64": true, "uint64": true, "int128": true, "uint128": true, "varint32": true, "varuint32": true,
+	"float32": true, "float64": true, "float128": true, "time_point": true, "time_point_sec": true,
+	"block_timestamp_type": true, "name": true, "bytes": true, "string": true, "checksum160": true,
+	"checksum256": true, "checksum512": true, "public_key": true, "signature": true, "symbol": true,
+	"symbol_code": true, "asset": true, "extended_asset": true,
+}
+
+var (
+	abiIntegerTypes = []string{"int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64", "varint32", "varuint32"}
+	// event_type is decoded to int
+	abiEventTypeTypes = []string{"int8", "uint8", "int16", "uint16", "int32", "uint32", "varint32", "varuint32"}
+
+	// the fields of the contract action decoded to RawEvent
+	rawEventFields = []struct {
+		name  string
+		types []string
+	}{
+		{"sender", []string{"name"}},
+		{"casino_id", abiIntegerTypes},
+		{"game_id", abiIntegerTypes},
+		{"req_id", abiIntegerTypes},
+		{"event_type", abiEventTypeTypes},
+		{"data", []string{"bytes"}},
+	}
+)
+
+func loadAbi(filename string) (*eos.ABI, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return eos.NewABI(f)
+}
+
+// resolveType returns the type of the alias, the decoder resolves one alias level only
+func resolveType(abi *eos.ABI, name string) string {
+	resolved, _ := abi.TypeNameForNewTypeName(name)
+	return resolved
+}
+
+// structFields returns the fields of the struct with the fields of the base structs first
+func structFields(abi *eos.ABI, name string) ([]eos.FieldDef, error) {
+	fields := make([]eos.FieldDef, 0)
+	for depth := 0; name != ""; depth++ {
+		structure := abi.StructForName(resolveType(abi, name))
+		if structure == nil {
+			return nil, fmt.Errorf("struct %s not found", name)
+		}
+		if depth > len(abi.Structs) {
+			return nil, fmt.Errorf("struct %s has recursive base", name)
+		}
+		fields = append(append(make([]eos.FieldDef, 0), structure.Fields...), fields...)
+		name = structure.Base
+	}
+	return fields, nil
+}
+
+// checkTypes reports the types the decoder does not know
+func checkTypes(issues *abiIssues, file string, abi *eos.ABI, structName string, visited map[string]bool) {
+	if visited[structName] {
+		return
+	}
+	visited[structName] = true
+
+	fields, err := structFields(abi, structName)
+	if err != nil {
+		issues.breaking(file, "%s", err)
+		return
+	}
+
+	for _, field := range fields {
+		fieldType := resolveType(abi, strings.TrimRight(field.Type, "?$"))
+		fieldType = resolveType(abi, strings.TrimSuffix(fieldType, "[]"))
+		switch {
+		case abiBuiltinTypes[fieldType]:
+		case abi.StructForName(fieldType) != nil:
+			checkTypes(issues, file, abi, fieldType, visited)
+		case abi.VariantForName(fieldType) != nil:
+		default:
+			issues.breaking(file, "struct %s field %s has unknown type %s", structName, field.Name, field.Type)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func checkMainAbi(issues *abiIssues, file string, actionName string) {
+	abi, err := loadAbi(file)
+	if err != nil {
+		issues.breaking(file, "%s", err)
+		return
+	}
+
+	action := abi.ActionForName(eos.ActionName(actionName))
+	if action == nil {
+		issues.breaking(file, "action %s not found", actionName)
+		return
+	}
+
+	fields, err := structFields(abi, action.Type)
+	if err != nil {
+		issues.breaking(file, "action %s: %s", actionName, err)
+		return
+	}
+
+	types := make(map[string]string)
+	for _, field := range fields {
+		types[field.Name] = resolveType(abi, field.Type)
+	}
+
+	for _, expected := range rawEventFields {
+		fieldType, ok := types[expected.name]
+		if !ok {
+			issues.breaking(file, "action %s has no field %s", actionName, expected.name)
+		} else if !contains(expected.types, fieldType) {
+			issues.breaking(file, "action %s field %s has type %s, expected %s", actionName, expected.name, fieldType, strings.Join(expected.types, " or "))
+		}
+	}
+
+	checkTypes(issues, file, abi, action.Type, make(map[string]bool))
+}
+
+func checkEventAbi(issues *abiIssues, file string) {
+	abi, err := loadAbi(file)
+	if err != nil {
+		issues.breaking(file, "%s", err)
+		return
+	}
+
+	if abi.StructForName(defaultEventStructName) == nil {
+		issues.breaking(file, "struct %s not found", defaultEventStructName)
+		return
+	}
+	checkTypes(issues, file, abi, defaultEventStructName, make(map[string]bool))
+}
+
+// CheckAbi checks the ABI files of every source: the main ABI has the contract action with the RawEvent fields,
+// the event ABIs have the event_data struct, all types are known to the decoder
+func CheckAbi(config *Config) []*AbiIssue {
+	issues := make(abiIssues, 0)
+	checked := make(map[string]bool)
+
+	for _, source := range config.getSources() {
+		actionName := defaultContractActionName
+		if source.filter.actName != nil {
+			actionName = *source.filter.actName
+		}
+
+		if key := source.abi.main + "#" + actionName; !checked[key] {
+			checked[key] = true
+			checkMainAbi(&issues, source.abi.main, actionName)
+		}
+
+		eventTypes := make([]int, 0, len(source.abi.events))
+		for eventType := range source.abi.events {
+			eventTypes = append(eventTypes, eventType)
+		}
+		sort.Ints(eventTypes)
+
+		for _, eventType := range eventTypes {
+			if file := source.abi.events[eventType]; !checked[file] {
+				checked[file] = true
+				checkEventAbi(&issues, file)
+			}
+		}
+	}
+
+	return issues
+}
+
+// DiffAbi compares the structs and actions of the new ABI with the old one. The binary data has no field names,
+// so removed, reordered and retyped fields are breaking, the appended fields are breaking
+// unless they are binary extensions (type$) decoded from the old data too.
+func DiffAbi(oldFile string, newFile string) ([]*AbiIssue, error) {
+	oldAbi, err := loadAbi(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newAbi, err := loadAbi(newFile)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make(abiIssues, 0)
+	for _, oldAction := range oldAbi.Actions {
+		newAction := newAbi.ActionForName(oldAction.Name)
+		if newAction == nil {
+			issues.breaking(newFile, "action %s removed", oldAction.Name)
+		} else if newAction.Type != oldAction.Type {
+			issues.breaking(newFile, "action %s type changed from %s to %s", oldAction.Name, oldAction.Type, newAction.Type)
+		}
+	}
+
+	for _, oldStruct := range oldAbi.Structs {
+		oldFields, err := structFields(oldAbi, oldStruct.Name)
+		if err != nil {
+			issues.breaking(oldFile, "%s", err)
+			continue
+		}
+
+		if newAbi.StructForName(oldStruct.Name) == nil {
+			issues.breaking(newFile, "struct %s removed", oldStruct.Name)
+			continue
+		}
+		newFields, err := structFields(newAbi, oldStruct.Name)
+		if err != nil {
+			issues.breaking(newFile, "%s", err)
+			continue
+		}
+
+		diffFields(&issues, newFile, oldStruct.Name, oldAbi, oldFields, newAbi, newFields)
+	}
+
+	return issues, nil
+}
+
+func diffFields(issues *abiIssues, file string, name string, oldAbi *eos.ABI, oldFields []eos.FieldDef, newAbi *eos.ABI, newFields []eos.FieldDef) {
+	for i, oldField := range oldFields {
+		if i >= len(newFields) {
+			issues.breaking(file, "struct %s field %s removed", name, oldField.Name)
+			continue
+		}
+
+		newField := newFields[i]
+		if newField.Name != oldField.Name {
+			issues.breaking(file, "struct %s field %d renamed or reordered from %s to %s", name, i, oldField.Name, newField.Name)
+		}
+		if oldType, newType := resolveType(oldAbi, oldField.Type), resolveType(newAbi, newField.Type); oldType != newType {
+			issues.breaking(file, "struct %s field %s type changed from %s to %s", name, oldField.Name, oldType, newType)
+		}
+	}
+
+	if len(newFields) <= len(oldFields) {
+		return
+	}
+	for _, newField := range newFields[len(oldFields):] {
+		if strings.HasSuffix(newField.Type, "$") {
+			issues.warning(file, "struct %s binary extension field %s added", name, newField.Name)
+		} else {
+			issues.breaking(file, "struct %s field %s added, the old data has no value, use binary extension %s$", name, newField.Name, newField.Type)
+		}
+	}
+}
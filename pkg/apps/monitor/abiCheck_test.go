@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestAbi(t *testing.T, dir string, name string, abi string) string {
+	filename := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(filename, []byte(abi), 0644))
+	return filename
+}
+
+const testEventAbiV2 = `{
+    "version": "eosio::abi/1.1",
+    "types": [{"new_type_name": "amount", "type": "uint64"}],
+    "structs": [{"name": "event_data", "base": "", "fields": [
+        {"name": "a", "type": "amount"},
+        {"name": "c", "type": "string"},
+        {"name": "d", "type": "uint32"},
+        {"name": "e", "type": "bool$"}
+    ]}]
+}`
+
+func TestCheckAbi(t *testing.T) {
+	config := newConfig()
+	assert.Empty(t, CheckAbi(config))
+
+	dir := t.TempDir()
+	config.abi.main = writeTestAbi(t, dir, "contract.abi", `{
+    "version": "eosio::abi/1.1",
+    "structs": [{"name": "send", "base": "", "fields": [
+        {"name": "sender", "type": "name"},
+        {"name": "casino_id", "type": "uint64"},
+        {"name": "game_id", "type": "uint64"},
+        {"name": "event_type", "type": "uint64"},
+        {"name": "data", "type": "bytes"},
+        {"name": "extra", "type": "game_info"}
+    ]}],
+    "actions": [{"name": "send", "type": "send"}]
+}`)
+	config.abi.events = map[int]string{
+		0: writeTestAbi(t, dir, "event_0.abi", `{"version": "eosio::abi/1.1", "structs": [{"name": "other", "base": "", "fields": []}]}`),
+		1: writeTestAbi(t, dir, "event_1.abi", testEventAbiV2),
+		2: filepath.Join(dir, "not_exists.abi"),
+	}
+
+	issues := CheckAbi(config)
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		assert.True(t, issue.Breaking)
+		messages = append(messages, issue.Message)
+	}
+	require.Len(t, messages, 5, messages)
+	assert.Equal(t, "action send has no field req_id", messages[0])
+	assert.Contains(t, messages[1], "action send field event_type has type uint64")
+	assert.Equal(t, "struct send field extra has unknown type game_info", messages[2])
+	assert.Equal(t, "struct event_data not found", messages[3])
+	assert.Contains(t, messages[4], "no such file")
+
+	send := "transfer"
+	config.sources = []*SourceConfig{{name: "test", filter: DatabaseFilters{actName: &send}}}
+	issues = CheckAbi(config)
+	require.NotEmpty(t, issues)
+	assert.Equal(t, "action transfer not found", issues[0].Message)
+}
+
+func TestDiffAbi(t *testing.T) {
+	dir := t.TempDir()
+	newFile := writeTestAbi(t, dir, "event_v2.abi", testEventAbiV2)
+
+	issues, err := DiffAbi(defaultEventABI, newFile)
+	require.NoError(t, err)
+
+	messages := make(map[string]bool)
+	for _, issue := range issues {
+		messages[issue.String()] = true
+	}
+	assert.Equal(t, map[string]bool{
+		"error: " + newFile + ": struct event_data field 1 renamed or reordered from b to c":   true,
+		"error: " + newFile + ": struct event_data field b type changed from uint32 to string": true,
+		"error: " + newFile + ": struct event_data field 2 renamed or reordered from c to d":   true,
+		"error: " + newFile + ": struct event_data field c type changed from string to uint32": true,
+		"warning: " + newFile + ": struct event_data binary extension field e added":           true,
+	}, messages)
+
+	issues, err = DiffAbi(defaultContractABI, defaultContractABI)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	_, err = DiffAbi(defaultEventABI, filepath.Join(dir, "not_exists.abi"))
+	assert.Error(t, err)
+}
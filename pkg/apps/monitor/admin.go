@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	adminDisconnectText = "disconnected by admin"
+	adminAuthPrefix     = "Bearer "
+)
+
+type AdminSession struct {
+	ID         string `json:"id"`
+	RemoteAddr string `json:"remote_addr"`
+	// sha256 prefix of the token, the id and the label of the token if checked
+	TokenHash   string    `json:"token_hash"`
+	TokenID     int       `json:"token_id,omitempty"`
+	TokenLabel  string    `json:"token_label,omitempty"`
+	Topics      []string  `json:"topics"`
+	Offset      uint64    `json:"offset"`
+	BufferDepth int       `json:"buffer_depth"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+type AdminTopic struct {
+	Name        string `json:"name"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// handleAdmin registers the admin API if the admin token is configured
+func (m *Monitor) handleAdmin(router *mux.Router) {
+	if m.config.admin.token == "" {
+		return
+	}
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(m.adminAuth)
+	admin.HandleFunc("/sessions", m.serveAdminSessions).Methods("GET")
+	admin.HandleFunc("/sessions/{id}", m.serveAdminDisconnect).Methods("DELETE")
+	admin.HandleFunc("/topics", m.serveAdminTopics).Methods("GET")
+	admin.HandleFunc("/export", m.serveAdminExport).Methods("GET")
+	m.log.handleLogLevels(admin)
+}
+
+// adminAuth checks the header Authorization: Bearer <admin token>
+func (m *Monitor) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, adminAuthPrefix) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		token := strings.TrimPrefix(authorization, adminAuthPrefix)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(m.config.admin.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Monitor) writeAdminResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.log.Main.Error("admin response error", zap.Error(err))
+	}
+}
+
+// GET /admin/sessions
+func (m *Monitor) serveAdminSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := m.sessionManager.list(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	topics, err := m.scraper.subscribers(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	sessionTopics := make(map[*Session][]string)
+	for name, topicSessions := range topics {
+		for _, session := range topicSessions {
+			sessionTopics[session] = append(sessionTopics[session], name)
+		}
+	}
+
+	response := make([]*AdminSession, 0, len(sessions))
+	for _, session := range sessions {
+		names := sessionTopics[session]
+		if names == nil {
+			names = make([]string, 0)
+		}
+		sort.Strings(names)
+
+		tokenHash, user := session.Token()
+		adminSession := &AdminSession{
+			ID:          session.ID,
+			RemoteAddr:  session.remoteAddr,
+			TokenHash:   tokenHash,
+			Topics:      names,
+			Offset:      session.Offset(),
+			BufferDepth: len(session.send),
+			ConnectedAt: session.connectedAt,
+		}
+		if user != nil {
+			adminSession.TokenID, adminSession.TokenLabel = user.ID, user.Label
+		}
+		response = append(response, adminSession)
+	}
+
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].ConnectedAt.Before(response[j].ConnectedAt)
+	})
+
+	m.writeAdminResponse(w, response)
+}
+
+// GET /admin/topics
+func (m *Monitor) serveAdminTopics(w http.ResponseWriter, r *http.Request) {
+	topics, err := m.scraper.subscribers(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	response := make([]*AdminTopic, 0, len(topics))
+	for name, topicSessions := range topics {
+		response = append(response, &AdminTopic{name, len(topicSessions)})
+	}
+
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Name < response[j].Name
+	})
+
+	m.writeAdminResponse(w, response)
+}
+
+// DELETE /admin/sessions/{id}
+func (m *Monitor) serveAdminDisconnect(w http.ResponseWriter, r *http.Request) {
+	ID := mux.Vars(r)["id"]
+
+	sessions, err := m.sessionManager.list(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	for _, session := range sessions {
+		if session.ID != ID {
+			continue
+		}
+
+		m.log.Main.Info("admin disconnect", zap.String("session.id", ID))
+		session.disconnect(closeReasonAdmin, websocket.ClosePolicyViolation, adminDisconnectText)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Error(w, "session not found", http.StatusNotFound)
+}
+
+// GET /admin/export?format=json|csv&from_offset=&to_offset=&from=&to=&topics=
+func (m *Monitor) serveAdminExport(w http.ResponseWriter, r *http.Request) {
+	request, err := parseExportQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Format == ExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="events.csv"`)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="events.jsonl"`)
+	}
+
+	// the status is sent with the first page, the errors after it only end the stream
+	stats, err := export(r.Context(), m.pool, m.metrics, m.config, m.abiDecoder, w, request)
+	if err != nil {
+		m.log.Main.Error("admin export error", zap.Error(err), zap.Uint64("offset", stats.NextOffset))
+		return
+	}
+	m.log.Main.Info("admin export",
+		zap.Int("events", stats.Events),
+		zap.Int("skipped", stats.Skipped),
+		zap.Uint64("offset", stats.NextOffset),
+	)
+}
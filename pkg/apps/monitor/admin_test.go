@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAdminToken = "admin"
+
+func adminRequest(t *testing.T, server *httptest.Server, method string, path string, result interface{}) int {
+	request, err := http.NewRequest(method, server.URL+path, nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	if result != nil && response.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(response.Body).Decode(result))
+	}
+	return response.StatusCode
+}
+
+func TestAdminDisabled(t *testing.T) {
+	monitor := newTestMonitor(t)
+
+	recorder := httptest.NewRecorder()
+	monitor.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/sessions", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAdminUnauthorized(t *testing.T) {
+	monitor := newTestMonitor(t)
+	monitor.config.admin.token = testAdminToken
+
+	for _, authorization := range []string{"Bearer wrong", testAdminToken, "Basic " + testAdminToken} {
+		request := httptest.NewRequest("GET", "/admin/topics", nil)
+		request.Header.Set("Authorization", authorization)
+
+		recorder := httptest.NewRecorder()
+		monitor.Handler().ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, authorization)
+	}
+}
+
+func TestAdminSessions(t *testing.T) {
+	monitor := newTestMonitor(t)
+	monitor.config.admin.token = testAdminToken
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+
+	server := httptest.NewServer(monitor.Handler())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, topic := range []string{"event_1", "event_0"} {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"id": "1", "method": "subscribe", "params": {"topic": "`+topic+`", "token": "token", "offset": 7}}`)))
+
+		_, message, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Contains(t, string(message), `"result":true`)
+	}
+
+	var sessions []*AdminSession
+	require.Equal(t, http.StatusOK, adminRequest(t, server, "GET", "/admin/sessions", &sessions))
+	require.Len(t, sessions, 1)
+	assert.Equal(t, hashToken("token")[:8], sessions[0].TokenHash)
+	assert.Zero(t, sessions[0].TokenID)
+	assert.Equal(t, []string{"event_0", "event_1"}, sessions[0].Topics)
+	assert.Equal(t, uint64(7), sessions[0].Offset)
+	assert.Zero(t, sessions[0].BufferDepth)
+	assert.NotEmpty(t, sessions[0].RemoteAddr)
+	assert.False(t, sessions[0].ConnectedAt.IsZero())
+
+	var topics []*AdminTopic
+	require.Equal(t, http.StatusOK, adminRequest(t, server, "GET", "/admin/topics", &topics))
+	assert.Equal(t, []*AdminTopic{{"event_0", 1}, {"event_1", 1}}, topics)
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, server, "DELETE", "/admin/sessions/unknown", nil))
+	assert.Equal(t, http.StatusNoContent, adminRequest(t, server, "DELETE", "/admin/sessions/"+sessions[0].ID, nil))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+
+	require.Eventually(t, func() bool {
+		adminRequest(t, server, "GET", "/admin/sessions", &sessions)
+		return len(sessions) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAdminExport(t *testing.T) {
+	monitor := newTestMonitor(t)
+	monitor.config.admin.token = testAdminToken
+	monitor.pool = newExportDatabase(t, monitor.abiDecoder, 3)
+
+	request := httptest.NewRequest("GET", "/admin/export?format=csv&topics=game_started", nil)
+	request.Header.Set("Authorization", "Bearer "+testAdminToken)
+	recorder := httptest.NewRecorder()
+	monitor.Handler().ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Len(t, strings.Split(strings.TrimSpace(recorder.Body.String()), "\n"), 3)
+
+	request = httptest.NewRequest("GET", "/admin/export?from_offset=x", nil)
+	request.Header.Set("Authorization", "Bearer "+testAdminToken)
+	recorder = httptest.NewRecorder()
+	monitor.Handler().ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -4,44 +4,66 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
+	"github.com/jackc/pgx/v4"
+	"time"
 )
 
-var errUserNotExists = errors.New("user not exist")
+var (
+	errUserNotExists   = errors.New("user not exist")
+	errTokenExpired    = errors.New("token expired")
+	errTopicNotAllowed = errors.New("topic is not in the token scopes")
+)
 
-func isUserExists(ctx context.Context, db DatabaseConnect, token string) (bool, error) {
-	cnt := 0
-	err := db.QueryRow(ctx, "SELECT count(token) FROM monitor.users WHERE token = $1", token).Scan(&cnt)
+// findUser returns the user of the token hash, the revoked and expired tokens are rejected
+func findUser(ctx context.Context, db DatabaseConnect, observer *metrics.Metrics, token string) (*User, error) {
+	defer observer.ObserveQuery(queryCheckToken, time.Now())
 
+	user, err := scanUser(db.QueryRow(ctx, sqlSelectUser, hashToken(token)))
+	if err == pgx.ErrNoRows {
+		return nil, errUserNotExists
+	}
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return !(cnt == 0), nil
+	if user.expired(time.Now()) {
+		return nil, errTokenExpired
+	}
+	return user, nil
 }
 
-func checkToken(parentContext context.Context, token string) error {
-	if config.skipTokenCheck { // for unit testing
-		return nil
-	}
+// checkToken checks the token and the topics are in its scopes
+func (m *Monitor) checkToken(parentContext context.Context, token string, topics ...string) error {
+	_, err := m.authorize(parentContext, token, topics...)
+	return err
+}
 
-	conn, err := sharedPool.Acquire(parentContext)
-	if err != nil {
-		return fmt.Errorf("shared pool acquire connection error: %s", err)
+// authorize returns the user of the token if the topics are in its scopes, nil without the token check
+func (m *Monitor) authorize(parentContext context.Context, token string, topics ...string) (*User, error) {
+	if m.config.skipTokenCheck { // for unit testing
+		m.metrics.TokenChecksTotal.WithLabelValues("skipped").Inc()
+		return nil, nil
 	}
 
-	defer func() {
-		conn.Release()
-	}()
-
-	var ok bool
-	ok, err = isUserExists(parentContext, conn, token)
-	if err != nil {
-		return fmt.Errorf("shared query error: %s", err)
+	user, err := findUser(parentContext, m.sharedPool, m.metrics, token)
+	switch err {
+	case nil:
+	case errUserNotExists, errTokenExpired:
+		m.metrics.TokenChecksTotal.WithLabelValues("rejected").Inc()
+		return nil, err
+	default:
+		m.metrics.TokenChecksTotal.WithLabelValues("error").Inc()
+		return nil, fmt.Errorf("shared query error: %s", err)
 	}
 
-	if !ok {
-		return errUserNotExists
+	for _, topic := range topics {
+		if !user.allowed(topic) {
+			m.metrics.TokenChecksTotal.WithLabelValues("rejected").Inc()
+			return nil, errTopicNotAllowed
+		}
 	}
 
-	return nil
+	m.metrics.TokenChecksTotal.WithLabelValues("ok").Inc()
+	return user, nil
 }
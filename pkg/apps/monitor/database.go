@@ -2,10 +2,77 @@ package monitor
 
 import (
 	"context"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
 )
 
+// Period of updating the pool metrics
+const poolStatsPeriod = 5 * time.Second
+
 type DatabaseConnect interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 	Query(ctx context.Context, sql string, optionsAndArgs ...interface{}) (pgx.Rows, error)
 }
+
+// DatabasePool is implemented by *pgxpool.Pool
+type DatabasePool interface {
+	DatabaseConnect
+	Close()
+}
+
+// DatabaseListener acquires a dedicated connection to LISTEN for notifications,
+// the scraper listens only if the monitor pool implements it
+type DatabaseListener interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// DatabaseNotifier delivers the notifications of the channel without a dedicated connection,
+// implemented by DatabaseMemory, the filters are removed by nil
+type DatabaseNotifier interface {
+	SetNotifyFilter(channel string, filters []DatabaseFilters)
+	Listen(ctx context.Context, channel string) <-chan *pgconn.Notification
+}
+
+// databaseStater is implemented by *pgxpool.Pool
+type databaseStater interface {
+	Stat() *pgxpool.Stat
+}
+
+func (m *Monitor) runPoolStats(parentContext context.Context) {
+	ticker := time.NewTicker(poolStatsPeriod)
+	defer ticker.Stop()
+
+	// the acquire counts of the pools at the last observation
+	acquires := make(map[string]int64)
+	for {
+		m.observePool("db", m.pool, acquires)
+		m.observePool("shared", m.sharedPool, acquires)
+
+		select {
+		case <-parentContext.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) observePool(name string, pool DatabasePool, acquires map[string]int64) {
+	stater, ok := pool.(databaseStater)
+	if !ok {
+		return
+	}
+
+	stat := stater.Stat()
+	m.metrics.PoolConns.WithLabelValues(name, "total").Set(float64(stat.TotalConns()))
+	m.metrics.PoolConns.WithLabelValues(name, "idle").Set(float64(stat.IdleConns()))
+	m.metrics.PoolConns.WithLabelValues(name, "acquired").Set(float64(stat.AcquiredConns()))
+	m.metrics.PoolConns.WithLabelValues(name, "constructing").Set(float64(stat.ConstructingConns()))
+	m.metrics.PoolConns.WithLabelValues(name, "max").Set(float64(stat.MaxConns()))
+	// the pool counts the acquires since its start
+	if count := stat.AcquireCount(); count > acquires[name] {
+		m.metrics.PoolAcquireTotal.WithLabelValues(name).Add(float64(count - acquires[name]))
+		acquires[name] = count
+	}
+}
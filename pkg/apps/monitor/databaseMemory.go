@@ -0,0 +1,537 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// size of the notification queue of a listener, the notify waits for the listener if it is full
+const memoryNotifyBuffer = 100
+
+var (
+	memoryPlaceholderRegexp = regexp.MustCompile(`^(.+)\$(\d+)$`)
+	memoryLimitRegexp       = regexp.MustCompile(`^ LIMIT \$(\d+)$`)
+	memoryIntervalRegexp    = regexp.MustCompile(`(\d+)\s*([a-z]+?)s?\b`)
+)
+
+// units of the postgres interval, a month is 30 days like in postgres
+var memoryIntervalUnits = map[string]time.Duration{
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"minute":      time.Minute,
+	"hour":        time.Hour,
+	"day":         24 * time.Hour,
+	"week":        7 * 24 * time.Hour,
+	"month":       30 * 24 * time.Hour,
+	"year":        365 * 24 * time.Hour,
+}
+
+// DatabaseMemoryAction is a row of chain.action_trace
+type DatabaseMemoryAction struct {
+	Offset     uint64
+	BlockNum   uint32
+	ActAccount string
+	ActName    string
+	ActData    []byte
+}
+
+// DatabaseMemory keeps the action traces and the block info in memory and evaluates the queries of the monitor,
+// the added actions are notified like by action_trace_notify_trigger
+type DatabaseMemory struct {
+	mutex sync.Mutex
+	// ordered by the offset
+	actions []*DatabaseMemoryAction
+	blocks  map[uint32]time.Time
+
+	notifyFilters map[string][]DatabaseFilters
+	listeners     map[string][]*memoryListener
+
+	// current time of the eventExpires condition
+	now func() time.Time
+}
+
+type memoryListener struct {
+	ctx           context.Context
+	notifications chan *pgconn.Notification
+}
+
+// memoryCondition matches an action and its block time, nil if the block is not inserted
+type memoryCondition func(action *DatabaseMemoryAction, blockTime *time.Time) bool
+
+func NewDatabaseMemory() *DatabaseMemory {
+	return &DatabaseMemory{
+		blocks:        make(map[uint32]time.Time),
+		notifyFilters: make(map[string][]DatabaseFilters),
+		listeners:     make(map[string][]*memoryListener),
+		now:           time.Now,
+	}
+}
+
+// AddBlock inserts the block info, the timestamp is stored in UTC like timestamp without time zone
+func (db *DatabaseMemory) AddBlock(blockNum uint32, timestamp time.Time) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.blocks[blockNum] = timestamp.UTC()
+}
+
+// AddAction inserts the action trace and notifies it like action_trace_notify_trigger:
+// the channels of the matching notify filters get the JSON payload, new_action_trace gets the offset
+// of the action matching no filter
+func (db *DatabaseMemory) AddAction(action *DatabaseMemoryAction) {
+	db.mutex.Lock()
+	index := sort.Search(len(db.actions), func(i int) bool {
+		return db.actions[i].Offset >= action.Offset
+	})
+	db.actions = append(db.actions, nil)
+	copy(db.actions[index+1:], db.actions[index:])
+	db.actions[index] = action
+
+	channels := make([]string, 0)
+	for channel, filters := range db.notifyFilters {
+		for _, filter := range filters {
+			if (filter.actAccount == nil || *filter.actAccount == action.ActAccount) &&
+				(filter.actName == nil || *filter.actName == action.ActName) {
+				channels = append(channels, channel)
+				break
+			}
+		}
+	}
+	db.mutex.Unlock()
+
+	if len(channels) == 0 {
+		db.Notify(defaultNotifyChannel, strconv.FormatUint(action.Offset, 10))
+		return
+	}
+
+	payload, _ := json.Marshal(&ActionNotification{Offset: action.Offset, ActAccount: action.ActAccount, ActName: action.ActName})
+	for _, channel := range channels {
+		db.Notify(channel, string(payload))
+	}
+}
+
+// SetNotifyFilter replaces the notify filters of the channel like syncNotifyFilter
+func (db *DatabaseMemory) SetNotifyFilter(channel string, filters []DatabaseFilters) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if len(filters) == 0 {
+		delete(db.notifyFilters, channel)
+		return
+	}
+	db.notifyFilters[channel] = filters
+}
+
+// Notify sends the payload to the listeners of the channel like pg_notify
+func (db *DatabaseMemory) Notify(channel string, payload string) {
+	db.mutex.Lock()
+	listeners := append([]*memoryListener(nil), db.listeners[channel]...)
+	db.mutex.Unlock()
+
+	for _, listener := range listeners {
+		select {
+		case <-listener.ctx.Done():
+		case listener.notifications <- &pgconn.Notification{Channel: channel, Payload: payload}:
+		}
+	}
+}
+
+// Listen returns the notifications of the channel until the context is done
+func (db *DatabaseMemory) Listen(ctx context.Context, channel string) <-chan *pgconn.Notification {
+	listener := &memoryListener{ctx, make(chan *pgconn.Notification, memoryNotifyBuffer)}
+
+	db.mutex.Lock()
+	db.listeners[channel] = append(db.listeners[channel], listener)
+	db.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		db.mutex.Lock()
+		defer db.mutex.Unlock()
+		listeners := db.listeners[channel]
+		for i := range listeners {
+			if listeners[i] == listener {
+				db.listeners[channel] = append(listeners[:i:i], listeners[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	return listener.notifications
+}
+
+func (db *DatabaseMemory) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if sql == sqlCheckDatabase {
+		return &memoryRow{values: []interface{}{1}}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if sql == sqlFetchChainHead {
+		var head *time.Time
+		var headNum uint32
+		for blockNum, timestamp := range db.blocks {
+			if head == nil || blockNum > headNum {
+				timestamp := timestamp
+				head, headNum = &timestamp, blockNum
+			}
+		}
+		if head == nil {
+			return &memoryRow{}
+		}
+		return &memoryRow{values: []interface{}{head}}
+	}
+
+	if where, tail, ok := memoryWhere(sqlFetchAction, sql); ok && tail == "" {
+		condition, err := db.parseWhere(where, args)
+		if err != nil {
+			return &memoryRow{err: err}
+		}
+		for _, action := range db.actions {
+			if blockTime := db.blockTime(action); condition(action, blockTime) {
+				return &memoryRow{values: memoryActionValues(action, blockTime)}
+			}
+		}
+		return &memoryRow{}
+	}
+
+	if where, tail, ok := memoryWhere(sqlFetchLastAction, sql); ok && tail == "" {
+		condition := func(*DatabaseMemoryAction, *time.Time) bool { return true }
+		if where != "" {
+			var err error
+			if condition, err = db.parseWhere(strings.TrimPrefix(where, "WHERE "), args); err != nil {
+				return &memoryRow{err: err}
+			}
+		}
+		for i := len(db.actions) - 1; i >= 0; i-- {
+			// the inner join skips the actions without the block
+			if blockTime := db.blockTime(db.actions[i]); blockTime != nil && condition(db.actions[i], blockTime) {
+				return &memoryRow{values: []interface{}{blockTime}}
+			}
+		}
+		return &memoryRow{}
+	}
+
+	return &memoryRow{err: fmt.Errorf("unsupported query: %s", sql)}
+}
+
+func (db *DatabaseMemory) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	where, tail, ok := memoryWhere(sqlFetchActions, sql)
+	if !ok {
+		return nil, fmt.Errorf("unsupported query: %s", sql)
+	}
+
+	var limit uint
+	if tail != "" {
+		match := memoryLimitRegexp.FindStringSubmatch(tail)
+		if match == nil {
+			return nil, fmt.Errorf("unsupported query tail: %s", tail)
+		}
+		value, err := memoryArg(args, match[1])
+		if err != nil {
+			return nil, err
+		}
+		if limit, ok = value.(uint); !ok {
+			return nil, fmt.Errorf("limit %T is not uint", value)
+		}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	condition, err := db.parseWhere(where, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &memoryRows{}
+	for _, action := range db.actions {
+		if limit != 0 && uint(len(rows.values)) == limit {
+			break
+		}
+		if blockTime := db.blockTime(action); blockTime != nil && condition(action, blockTime) {
+			rows.values = append(rows.values, memoryActionValues(action, blockTime))
+		}
+	}
+	return rows, nil
+}
+
+func (db *DatabaseMemory) Close() {}
+
+// deleteBefore removes the actions and the blocks older than the block time, the actions without the block stay
+func (db *DatabaseMemory) deleteBefore(blockTime time.Time) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	actions := db.actions[:0]
+	for _, action := range db.actions {
+		if timestamp := db.blockTime(action); timestamp == nil || !timestamp.Before(blockTime) {
+			actions = append(actions, action)
+		}
+	}
+	for i := len(actions); i < len(db.actions); i++ {
+		db.actions[i] = nil
+	}
+	db.actions = actions
+
+	for blockNum, timestamp := range db.blocks {
+		if timestamp.Before(blockTime) {
+			delete(db.blocks, blockNum)
+		}
+	}
+}
+
+func (db *DatabaseMemory) blockTime(action *DatabaseMemoryAction) *time.Time {
+	if timestamp, ok := db.blocks[action.BlockNum]; ok {
+		return &timestamp
+	}
+	return nil
+}
+
+// memoryActionValues are the columns of sqlFetchAction and sqlFetchActions
+func memoryActionValues(action *DatabaseMemoryAction, blockTime *time.Time) []interface{} {
+	return []interface{}{action.ActData, action.Offset, blockTime, action.ActAccount, action.ActName}
+}
+
+// memoryWhere splits the query made from the format with the where clause into the where clause and the tail
+func memoryWhere(format string, sql string) (string, string, bool) {
+	parts := strings.SplitN(format, "%s", 2)
+	if !strings.HasPrefix(sql, parts[0]) {
+		return "", "", false
+	}
+	rest := sql[len(parts[0]):]
+	index := strings.Index(rest, parts[1])
+	if index < 0 {
+		return "", "", false
+	}
+	return rest[:index], rest[index+len(parts[1]):], true
+}
+
+func memoryArg(args []interface{}, number string) (interface{}, error) {
+	index, err := strconv.Atoi(number)
+	if err != nil || index < 1 || index > len(args) {
+		return nil, fmt.Errorf("argument $%s of %d", number, len(args))
+	}
+	return args[index-1], nil
+}
+
+// splitConditions splits the where clause by AND out of the parentheses
+func splitConditions(where string) []string {
+	conditions := make([]string, 0)
+	depth, start := 0, 0
+	for i := 0; i < len(where); i++ {
+		switch {
+		case where[i] == '(':
+			depth++
+		case where[i] == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(where[i:], sqlWhereAnd):
+			conditions = append(conditions, where[start:i])
+			start = i + len(sqlWhereAnd)
+			i = start - 1
+		}
+	}
+	return append(conditions, where[start:])
+}
+
+// parseWhere evaluates the where clause built by SqlQuery
+func (db *DatabaseMemory) parseWhere(where string, args []interface{}) (memoryCondition, error) {
+	conditions := make([]memoryCondition, 0)
+	for _, text := range splitConditions(where) {
+		condition, err := db.parseCondition(text, args)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return func(action *DatabaseMemoryAction, blockTime *time.Time) bool {
+		for _, condition := range conditions {
+			if !condition(action, blockTime) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (db *DatabaseMemory) parseCondition(text string, args []interface{}) (memoryCondition, error) {
+	// the filters of newSqlQuery: ((a AND b) OR (c))
+	if strings.HasPrefix(text, "((") && strings.HasSuffix(text, "))") {
+		groups := make([]memoryCondition, 0)
+		for _, group := range strings.Split(text[2:len(text)-2], ")"+sqlWhereOr+"(") {
+			condition, err := db.parseWhere(group, args)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, condition)
+		}
+		return func(action *DatabaseMemoryAction, blockTime *time.Time) bool {
+			for _, condition := range groups {
+				if condition(action, blockTime) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	expires := strings.SplitN(sqlWhereEventExpires, "%s", 2)
+	if strings.HasPrefix(text, expires[0]) && strings.HasSuffix(text, expires[1]) {
+		interval, err := parseInterval(text[len(expires[0]) : len(text)-len(expires[1])])
+		if err != nil {
+			return nil, err
+		}
+		from := db.now().UTC().Add(-interval)
+		return func(action *DatabaseMemoryAction, blockTime *time.Time) bool {
+			return blockTime != nil && blockTime.After(from)
+		}, nil
+	}
+
+	match := memoryPlaceholderRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("unsupported condition: %s", text)
+	}
+	arg, err := memoryArg(args, match[2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch match[1] {
+	case sqlWhereActAccount, sqlWhereActName:
+		value, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s argument %T is not string", match[1], arg)
+		}
+		if match[1] == sqlWhereActAccount {
+			return func(action *DatabaseMemoryAction, _ *time.Time) bool { return action.ActAccount == value }, nil
+		}
+		return func(action *DatabaseMemoryAction, _ *time.Time) bool { return action.ActName == value }, nil
+
+	case sqlWhereOffset, sqlWhereFromOffset, sqlWhereToOffset:
+		offset, ok := arg.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("%s argument %T is not uint64", match[1], arg)
+		}
+		switch match[1] {
+		case sqlWhereOffset:
+			return func(action *DatabaseMemoryAction, _ *time.Time) bool { return action.Offset == offset }, nil
+		case sqlWhereFromOffset:
+			return func(action *DatabaseMemoryAction, _ *time.Time) bool { return action.Offset >= offset }, nil
+		}
+		return func(action *DatabaseMemoryAction, _ *time.Time) bool { return action.Offset <= offset }, nil
+
+	case sqlWhereFromTime, sqlWhereToTime:
+		value, ok := arg.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("%s argument %T is not time", match[1], arg)
+		}
+		if match[1] == sqlWhereFromTime {
+			return func(_ *DatabaseMemoryAction, blockTime *time.Time) bool {
+				return blockTime != nil && !blockTime.Before(value)
+			}, nil
+		}
+		return func(_ *DatabaseMemoryAction, blockTime *time.Time) bool {
+			return blockTime != nil && blockTime.Before(value)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported condition: %s", text)
+}
+
+// parseInterval parses the intervals accepted by the eventExpires validation
+func parseInterval(text string) (time.Duration, error) {
+	if !eventExpiresRegexp.MatchString(text) {
+		return 0, fmt.Errorf("invalid interval: %q", text)
+	}
+
+	var interval time.Duration
+	for _, match := range memoryIntervalRegexp.FindAllStringSubmatch(text, -1) {
+		count, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval: %q", text)
+		}
+		interval += time.Duration(count) * memoryIntervalUnits[match[2]]
+	}
+	return interval, nil
+}
+
+type memoryRow struct {
+	values []interface{}
+	err    error
+}
+
+func (r *memoryRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.values == nil {
+		return pgx.ErrNoRows
+	}
+	return scanValues(r.values, dest)
+}
+
+type memoryRows struct {
+	DatabaseMockRows
+	values  [][]interface{}
+	current []interface{}
+}
+
+func (r *memoryRows) Next() bool {
+	if len(r.values) == 0 {
+		return false
+	}
+	r.current, r.values = r.values[0], r.values[1:]
+	return true
+}
+
+func (r *memoryRows) Scan(dest ...interface{}) error {
+	return scanValues(r.current, dest)
+}
+
+func (r *memoryRows) Values() ([]interface{}, error) {
+	return r.current, nil
+}
+
+func (r *memoryRows) Err() error {
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// scanValues assigns the values to the pointers like pgx, NULL time can be scanned into **time.Time only
+func scanValues(values []interface{}, dest []interface{}) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("%d values scanned into %d destinations", len(values), len(dest))
+	}
+
+	for i, value := range values {
+		target := reflect.ValueOf(dest[i])
+		if target.Kind() != reflect.Ptr || target.IsNil() {
+			return fmt.Errorf("destination %d %T is not a pointer", i, dest[i])
+		}
+
+		source := reflect.ValueOf(value)
+		if blockTime, ok := value.(*time.Time); ok && target.Elem().Type() == timeType {
+			if blockTime == nil {
+				return fmt.Errorf("can't scan NULL into %T", dest[i])
+			}
+			source = reflect.ValueOf(*blockTime)
+		}
+		if !source.Type().AssignableTo(target.Elem().Type()) {
+			return fmt.Errorf("can't scan %T into %T", value, dest[i])
+		}
+		target.Elem().Set(source)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var testBlockTime = time.Date(2020, 10, 19, 12, 0, 0, 0, time.UTC)
+
+// newMemoryDatabase has the actions from offset 10 to 14 of casino::send in the blocks 1 to 5 one second apart,
+// the block of the last action is not inserted, the time is a minute after the first block
+func newMemoryDatabase(t *testing.T, decoder *AbiDecoder) *DatabaseMemory {
+	db := NewDatabaseMemory()
+	db.now = func() time.Time {
+		return testBlockTime.Add(time.Minute)
+	}
+	actData := encodeTestAction(t, decoder, createStructData(t, 1, 2, "test_string"))
+	for i := 0; i < 5; i++ {
+		if i < 4 {
+			db.AddBlock(uint32(i+1), testBlockTime.Add(time.Duration(i)*time.Second))
+		}
+		db.AddAction(&DatabaseMemoryAction{Offset: uint64(10 + i), BlockNum: uint32(i + 1), ActAccount: "casino", ActName: defaultContractActionName, ActData: actData})
+	}
+	return db
+}
+
+// newTestMemoryMonitor creates a monitor with the default config and the memory database
+func newTestMemoryMonitor(t *testing.T) (*Monitor, *DatabaseMemory) {
+	config := newConfig()
+	config.skipTokenCheck = true
+
+	abiDecoder, err := NewAbiDecoder(config, testLoggers.Decoder)
+	require.NoError(t, err)
+
+	db := newMemoryDatabase(t, abiDecoder)
+	return NewMonitor(config, abiDecoder, db, db, testLoggers, nil), db
+}
+
+func TestDatabaseMemoryAddAction(t *testing.T) {
+	db := NewDatabaseMemory()
+	for _, offset := range []uint64{12, 10, 11} {
+		db.AddAction(&DatabaseMemoryAction{Offset: offset})
+	}
+	offsets := make([]uint64, 0)
+	for _, action := range db.actions {
+		offsets = append(offsets, action.Offset)
+	}
+	assert.Equal(t, []uint64{10, 11, 12}, offsets)
+}
+
+func TestDatabaseMemoryQuery(t *testing.T) {
+	monitor := newTestMonitor(t)
+	db := newMemoryDatabase(t, monitor.abiDecoder)
+	ctx := context.Background()
+
+	one := 0
+	require.NoError(t, db.QueryRow(ctx, sqlCheckDatabase).Scan(&one))
+	assert.Equal(t, 1, one)
+
+	var head time.Time
+	require.NoError(t, db.QueryRow(ctx, sqlFetchChainHead).Scan(&head))
+	assert.Equal(t, testBlockTime.Add(3*time.Second), head)
+
+	assert.Error(t, db.QueryRow(ctx, "SELECT * FROM chain.action_trace").Scan(&one))
+	_, err := db.Query(ctx, "SELECT * FROM chain.action_trace")
+	assert.Error(t, err)
+
+	// the limit is not uint
+	_, err = db.Query(ctx, sqlFetchActions+" LIMIT $2", uint64(10), 1)
+	assert.Error(t, err)
+}
+
+func TestDatabaseMemoryNotify(t *testing.T) {
+	db := NewDatabaseMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// without the notify filters the offset is notified to new_action_trace
+	all := db.Listen(ctx, defaultNotifyChannel)
+	db.AddAction(&DatabaseMemoryAction{Offset: 10, ActAccount: "casino", ActName: "send"})
+
+	notification := <-all
+	assert.Equal(t, defaultNotifyChannel, notification.Channel)
+	assert.Equal(t, "10", notification.Payload)
+
+	// the actions matching the notify filters are notified to the filter channels only
+	account := "casino"
+	db.SetNotifyFilter("test", []DatabaseFilters{{actAccount: &account}})
+	filtered := db.Listen(ctx, "test")
+	db.AddAction(&DatabaseMemoryAction{Offset: 11, ActAccount: "other", ActName: "send"})
+	db.AddAction(&DatabaseMemoryAction{Offset: 12, ActAccount: "casino", ActName: "send"})
+
+	assert.Equal(t, "11", (<-all).Payload)
+	notification = <-filtered
+	action, err := parseNotification(notification.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, &ActionNotification{Offset: 12, ActAccount: "casino", ActName: "send", hasAction: true}, action)
+	assertNoNotification(t, filtered)
+	assertNoNotification(t, all)
+
+	// the listener is removed when its context is done
+	listenContext, stopListen := context.WithCancel(ctx)
+	db.Listen(listenContext, "test")
+	stopListen()
+	require.Eventually(t, func() bool {
+		db.mutex.Lock()
+		defer db.mutex.Unlock()
+		return len(db.listeners["test"]) == 1
+	}, time.Second, 10*time.Millisecond)
+	db.Notify("test", "10")
+	assert.Equal(t, "10", (<-filtered).Payload)
+
+	// without the filters of the channel the actions go to new_action_trace again
+	db.SetNotifyFilter("test", nil)
+	db.AddAction(&DatabaseMemoryAction{Offset: 13, ActAccount: "casino", ActName: "send"})
+	assert.Equal(t, "13", (<-all).Payload)
+	assertNoNotification(t, filtered)
+}
+
+func assertNoNotification(t *testing.T, notifications <-chan *pgconn.Notification) {
+	select {
+	case notification := <-notifications:
+		t.Errorf("unexpected notification %s", notification.Payload)
+	default:
+	}
+}
+
+func TestDatabaseMemoryDeleteBefore(t *testing.T) {
+	monitor := newTestMonitor(t)
+	db := newMemoryDatabase(t, monitor.abiDecoder)
+
+	db.deleteBefore(testBlockTime.Add(2 * time.Second))
+	offsets := make([]uint64, 0)
+	for _, action := range db.actions {
+		offsets = append(offsets, action.Offset)
+	}
+	// the action without the block stays
+	assert.Equal(t, []uint64{12, 13, 14}, offsets)
+	assert.Equal(t, 2, len(db.blocks))
+}
+
+func TestParseInterval(t *testing.T) {
+	interval, err := parseInterval(defaultEventExpires)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, interval)
+
+	interval, err = parseInterval("2 days 12 hours 1 minute")
+	require.NoError(t, err)
+	assert.Equal(t, 60*time.Hour+time.Minute, interval)
+
+	_, err = parseInterval("1 fortnight")
+	assert.Error(t, err)
+}
+
+func TestScanValues(t *testing.T) {
+	var blockTime time.Time
+	var nullTime *time.Time
+	var count uint64
+	require.NoError(t, scanValues([]interface{}{&testBlockTime, (*time.Time)(nil), uint64(1)}, []interface{}{&blockTime, &nullTime, &count}))
+	assert.Equal(t, testBlockTime, blockTime)
+	assert.Nil(t, nullTime)
+	assert.Equal(t, uint64(1), count)
+
+	assert.Error(t, scanValues([]interface{}{(*time.Time)(nil)}, []interface{}{&blockTime}))
+	assert.Error(t, scanValues([]interface{}{"casino"}, []interface{}{&count}))
+	assert.Error(t, scanValues([]interface{}{"casino"}, []interface{}{count}))
+	assert.Error(t, scanValues([]interface{}{"casino"}, []interface{}{}))
+	assert.Equal(t, pgx.ErrNoRows, (&memoryRow{}).Scan(&count))
+}
+
+func TestScraperListenMemory(t *testing.T) {
+	monitor, db := newTestMemoryMonitor(t)
+	actName := defaultContractActionName
+	monitor.config.db.filter.actName = &actName
+	session := newSession(monitor, nil)
+	subscribe := &ScraperSubscribeMessage{name: eventTopicName(0), session: session, response: make(chan *ScraperResponseMessage)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+	go monitor.scraper.run(ctx)
+	monitor.scraper.subscribe <- subscribe
+	<-subscribe.response
+	require.Eventually(t, monitor.scraper.listening.IsSet, time.Second, 10*time.Millisecond)
+
+	// the invalid payload and the action out of the sources are skipped
+	db.Notify(defaultNotifyChannel, "invalid")
+	db.AddAction(&DatabaseMemoryAction{Offset: 20, BlockNum: 1, ActAccount: "casino", ActName: "other"})
+	actData := encodeTestAction(t, monitor.abiDecoder, createStructData(t, 1, 2, "test_string"))
+	db.AddAction(&DatabaseMemoryAction{Offset: 21, BlockNum: 2, ActAccount: "casino", ActName: defaultContractActionName, ActData: actData})
+
+	select {
+	case event := <-session.queue:
+		assert.Equal(t, uint64(21), event.Offset)
+		assert.Equal(t, testBlockTime.Add(time.Second), event.BlockTime)
+		assert.Equal(t, uint64(2), event.GameID)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+	assert.Equal(t, uint64(21), monitor.scraper.offset)
+}
+
+func TestScraperListenMemoryNotifyFilter(t *testing.T) {
+	monitor, db := newTestMemoryMonitor(t)
+	monitor.config.db.channel = "test"
+	monitor.config.db.notifyFilter = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.scraper.run(ctx)
+	require.Eventually(t, monitor.scraper.listening.IsSet, time.Second, 10*time.Millisecond)
+
+	db.mutex.Lock()
+	assert.Equal(t, sourceFilters(monitor.config.getSources()), db.notifyFilters["test"])
+	db.mutex.Unlock()
+
+	// the filters are removed on stop
+	monitor.scraper.stopListen()
+	require.Eventually(t, func() bool {
+		db.mutex.Lock()
+		defer db.mutex.Unlock()
+		_, ok := db.notifyFilters["test"]
+		return !ok && !monitor.scraper.listening.IsSet()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSessionSubscribeMemory(t *testing.T) {
+	monitor, _ := newTestMemoryMonitor(t)
+	session := newSession(monitor, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := make(chan *dataToSocket, 1)
+	go func() {
+		data := <-session.send
+		messages <- data
+		data.done <- struct{}{}
+	}()
+
+	// the action without the block is not sent
+	require.NoError(t, session.sendEventsFromDatabase(ctx, eventTopicName(0), 11))
+	data := <-messages
+
+	message := struct {
+		Result *EventMessage `json:"result"`
+	}{}
+	require.NoError(t, json.Unmarshal(data.data, &message))
+	offsets := make([]uint64, 0)
+	for _, event := range message.Result.Events {
+		offsets = append(offsets, event.Offset)
+	}
+	assert.Equal(t, []uint64{11, 12, 13}, offsets)
+	assert.Equal(t, uint64(13), session.Offset())
+}
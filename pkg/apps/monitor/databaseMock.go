@@ -44,3 +44,4 @@ func (m *DatabaseMock) QueryRow(ctx context.Context, sql string, args ...interfa
 func (m *DatabaseMock) Query(ctx context.Context, sql string, optionsAndArgs ...interface{}) (pgx.Rows, error) {
 	return new(DatabaseMockRows), nil
 }
+func (m *DatabaseMock) Close() {}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgx/v4"
+)
+
+// DecodeResult is the step by step decoding of act_data for the decode command
+type DecodeResult struct {
+	Offset     uint64         `json:"offset,omitempty"`
+	ActAccount string         `json:"act_account,omitempty"`
+	ActName    string         `json:"act_name,omitempty"`
+	Source     string         `json:"source"`
+	ActData    EventDataSlice `json:"act_data"`
+	RawEvent   *RawEvent      `json:"raw_event,omitempty"`
+	Event      *Event         `json:"event,omitempty"`
+	// eg the bytes left after decoding, the ABI may not match the data
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// unusedBytes returns the bytes left after the successful decoding,
+// the decoding reads forward only, so every prefix longer than the used bytes is decoded too
+func unusedBytes(decode func(data []byte) error, data []byte) int {
+	low, high := 0, len(data)
+	for low < high {
+		middle := (low + high) / 2
+		if decode(data[:middle]) == nil {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+	return len(data) - low
+}
+
+// DecodeActData decodes act_data with the decoder of the source,
+// on error the result has the parts decoded before the failed step
+func (a *AbiDecoder) DecodeActData(source string, data []byte) (*DecodeResult, error) {
+	result := &DecodeResult{Source: source, ActData: data}
+
+	decoder, err := a.source(source)
+	if err != nil {
+		return result, err
+	}
+
+	raw, err := decoder.decodeEvent(data)
+	if err != nil {
+		return result, err
+	}
+	result.RawEvent = raw
+
+	unused := unusedBytes(func(data []byte) error {
+		_, err := decoder.main.abi.DecodeAction(data, eos.ActionName(decoder.actionName))
+		return err
+	}, data)
+	if unused > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d bytes of act_data are not decoded by action %s of %s", unused, decoder.actionName, decoder.main.filename))
+	}
+
+	eventData, err := decoder.decodeEventData(raw.EventType, raw.Data)
+	if err != nil {
+		return result, err
+	}
+
+	eventDecoder := decoder.events[raw.EventType]
+	unused = unusedBytes(func(data []byte) error {
+		_, err := eventDecoder.abi.Decode(eos.NewDecoder(data), defaultEventStructName)
+		return err
+	}, raw.Data)
+	if unused > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d bytes of data are not decoded by struct %s of %s", unused, defaultEventStructName, eventDecoder.filename))
+	}
+
+	event, err := raw.ToEvent(eventData)
+	if err != nil {
+		return result, err
+	}
+	event.Source = source
+	result.Event = event
+	return result, nil
+}
+
+// DecodeOffset fetches the action by offset and decodes it with the decoder of the matching source
+func DecodeOffset(ctx context.Context, db DatabaseConnect, config *Config, decoder *AbiDecoder, offset uint64) (*DecodeResult, error) {
+	rows, err := fetchActionData(ctx, db, nil, offset, nil)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("no action with offset %d", offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source, ok := sourceOf(config.getSources(), rows.actAccount, rows.actName)
+	if !ok {
+		return nil, fmt.Errorf("action %s::%s with offset %d is not in the sources", rows.actAccount, rows.actName, offset)
+	}
+
+	result, err := decoder.DecodeActData(source.name, rows.actData)
+	result.Offset, result.ActAccount, result.ActName = rows.offset, rows.actAccount, rows.actName
+	if result.Event != nil {
+		result.Event.Offset, result.Event.BlockTime = rows.offset, rows.blockTime
+	}
+	return result, err
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func encodeTestAction(t *testing.T, decoder *AbiDecoder, data []byte) []byte {
+	actionJson := fmt.Sprintf(`{"sender":"test","casino_id":1,"game_id":2,"req_id":3,"event_type":0,"data":"%s"}`, hex.EncodeToString(data))
+	actData, err := decoder.main.abi.EncodeAction(eos.ActionName(defaultContractActionName), []byte(actionJson))
+	require.NoError(t, err)
+	return actData
+}
+
+func TestDecodeActData(t *testing.T) {
+	monitor := newTestMonitor(t)
+	decoder := monitor.abiDecoder
+
+	actData := encodeTestAction(t, decoder, createStructData(t, 1, 2, "test_string"))
+	result, err := decoder.DecodeActData(defaultSourceName, actData)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.RawEvent.EventType)
+	assert.Equal(t, uint64(2), result.Event.GameID)
+	assert.JSONEq(t, `{"a":1,"b":2,"c":"test_string"}`, string(result.Event.Data))
+	assert.Empty(t, result.Warnings)
+
+	// trailing bytes of the event data
+	actData = encodeTestAction(t, decoder, append(createStructData(t, 1, 2, "test_string"), 1, 2, 3))
+	result, err = decoder.DecodeActData(defaultSourceName, actData)
+	require.NoError(t, err)
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "3 bytes of data")
+
+	// uint32 b is cut
+	actData = encodeTestAction(t, decoder, createStructData(t, 1, 2, "test_string")[:10])
+	result, err = decoder.DecodeActData(defaultSourceName, actData)
+	require.IsType(t, &DecodeError{}, err)
+	decodeError := err.(*DecodeError)
+	assert.Equal(t, 8, decodeError.Pos)
+	assert.Equal(t, 10, decodeError.Size)
+	assert.Equal(t, "struct "+defaultEventStructName, decodeError.Struct)
+	assert.Equal(t, defaultEventABI, decodeError.Abi)
+	assert.NotNil(t, result.RawEvent)
+	assert.Nil(t, result.Event)
+
+	_, err = decoder.DecodeActData(defaultSourceName, actData[:3])
+	require.IsType(t, &DecodeError{}, err)
+	assert.Contains(t, err.Error(), "action "+defaultContractActionName)
+	assert.Equal(t, 0, err.(*DecodeError).Pos)
+
+	// uint64 game_id after sender and casino_id is cut
+	_, err = decoder.DecodeActData(defaultSourceName, actData[:19])
+	require.IsType(t, &DecodeError{}, err)
+	assert.Equal(t, 16, err.(*DecodeError).Pos)
+
+	_, err = decoder.DecodeActData("unknown", actData)
+	assert.Error(t, err)
+}
+
+func TestDecodeOffset(t *testing.T) {
+	monitor := newTestMonitor(t)
+	actData := encodeTestAction(t, monitor.abiDecoder, createStructData(t, 1, 2, "test_string"))
+
+	db := &sourceDatabase{actData: actData, actAccount: "casino"}
+	result, err := DecodeOffset(context.Background(), db, monitor.config, monitor.abiDecoder, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), result.Offset)
+	assert.Equal(t, "casino", result.ActAccount)
+	assert.Equal(t, uint64(10), result.Event.Offset)
+
+	send := "other"
+	monitor.config.sources = []*SourceConfig{{name: "test", filter: DatabaseFilters{actName: &send}}}
+	_, err = DecodeOffset(context.Background(), db, monitor.config, monitor.abiDecoder, 10)
+	assert.Error(t, err)
+}
@@ -2,9 +2,11 @@ package monitor
 
 import (
 	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
 	"github.com/eoscanada/eos-go"
 	"go.uber.org/zap"
 	"os"
+	"strconv"
 )
 
 const (
@@ -13,36 +15,97 @@ const (
 )
 
 type Decoder struct {
-	abi *eos.ABI
+	abi      *eos.ABI
+	log      *zap.Logger
+	filename string
+}
+
+// DecodeError is the ABI decoding error with the struct and the byte position of the failed read
+type DecodeError struct {
+	Abi    string
+	Struct string
+	// position in the decoded data, -1 if unknown
+	Pos  int
+	Size int
+	Err  error
+}
+
+func (d *Decoder) newDecodeError(label string, structName string, data []byte, err error) *DecodeError {
+	return &DecodeError{d.filename, label, failedFieldPos(d.abi, structName, data), len(data), err}
+}
+
+// failedFieldPos returns the position of the first field of the struct that is not decoded, -1 if unknown,
+// the fields before it are decoded as a struct with the same name and measured by unusedBytes
+func failedFieldPos(abi *eos.ABI, structName string, data []byte) int {
+	structure := abi.StructForName(structName)
+	if structure == nil {
+		return -1
+	}
+
+	// the first struct with the name is found first
+	prefix := *abi
+	pos := -1
+	for fields := 0; fields <= len(structure.Fields); fields++ {
+		prefix.Structs = append([]eos.StructDef{{Name: structName, Base: structure.Base, Fields: structure.Fields[:fields]}}, abi.Structs...)
+		decode := func(data []byte) error {
+			_, err := prefix.Decode(eos.NewDecoder(data), structName)
+			return err
+		}
+
+		if decode(data) != nil {
+			return pos
+		}
+		pos = len(data) - unusedBytes(decode, data)
+	}
+
+	// every field is decoded
+	return -1
+}
+
+func (e *DecodeError) Error() string {
+	if e.Pos < 0 {
+		return fmt.Sprintf("decode %s of %s: %s", e.Struct, e.Abi, e.Err)
+	}
+	return fmt.Sprintf("decode %s of %s at byte %d of %d: %s", e.Struct, e.Abi, e.Pos, e.Size, e.Err)
 }
 
 type AbiDecoder struct {
 	main   *Decoder
 	events map[int]*Decoder
+	log    *zap.Logger
+	// contract action with the event
+	actionName string
+
+	// decoders by source name, nil if the decoder is created without config
+	sources map[string]*AbiDecoder
 }
 
-func newDecoder(filename string) (*Decoder, error) {
+func newDecoder(filename string, log *zap.Logger) (*Decoder, error) {
 	f, err := os.Open(filename)
 	if err != nil {
-		decoderLog.Error("decoder file", zap.String("filename", filename), zap.Error(err))
+		log.Error("decoder file", zap.String("filename", filename), zap.Error(err))
 		return nil, err
 	}
 	defer f.Close()
 
 	abi, err := eos.NewABI(f)
 	if err != nil {
-		decoderLog.Error("decoder newABI", zap.String("filename", filename), zap.Error(err))
+		log.Error("decoder newABI", zap.String("filename", filename), zap.Error(err))
 		return nil, err
 	}
 
-	return &Decoder{abi}, nil
+	return &Decoder{abi, log, filename}, nil
 }
 
 func (d *Decoder) decodeAction(data []byte, actionName string) ([]byte, error) {
 	bytes, err := d.abi.DecodeAction(data, eos.ActionName(actionName))
 	if err != nil {
-		decoderLog.Error("decoder action", zap.String("action", actionName), zap.Error(err))
-		return nil, err
+		d.log.Error("decoder action", zap.String("action", actionName), zap.Error(err))
+		structName := ""
+		if action := d.abi.ActionForName(eos.ActionName(actionName)); action != nil {
+			structName = action.Type
+		}
+		return nil, d.newDecodeError("action "+actionName, structName, data, err)
 	}
 	return bytes, nil
 }
@@ -50,23 +113,23 @@ func (d *Decoder) decodeAction(data []byte, actionName string) ([]byte, error) {
 func (d *Decoder) decodeStruct(data []byte, structName string) ([]byte, error) {
 	bytes, err := d.abi.Decode(eos.NewDecoder(data), structName)
 	if err != nil {
-		decoderLog.Error("decoder struct", zap.String("struct", structName), zap.Error(err))
-		return nil, err
+		d.log.Error("decoder struct", zap.String("struct", structName), zap.Error(err))
+		return nil, d.newDecodeError("struct "+structName, structName, data, err)
 	}
 
 	return bytes, nil
 }
 
-func newAbiDecoder(c *AbiConfig) (a *AbiDecoder, e error) {
-	a = new(AbiDecoder)
-	a.main, e = newDecoder(c.main)
+func newAbiDecoder(c *AbiConfig, log *zap.Logger) (a *AbiDecoder, e error) {
+	a = &AbiDecoder{log: log, actionName: defaultContractActionName}
+	a.main, e = newDecoder(c.main, log)
 	if e != nil {
 		return
 	}
 
 	a.events = make(map[int]*Decoder)
 	for eventType, contractFileName := range c.events {
-		a.events[eventType], e = newDecoder(contractFileName)
+		a.events[eventType], e = newDecoder(contractFileName, log)
 		if e != nil {
 			return
 		}
@@ -75,12 +138,62 @@ func newAbiDecoder(c *AbiConfig) (a *AbiDecoder, e error) {
 	return
 }
 
+// NewAbiDecoder loads the contract and event ABI files listed in the config
+func NewAbiDecoder(config *Config, log *zap.Logger) (*AbiDecoder, error) {
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	decoder, err := newAbiDecoder(&config.abi, log)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder.sources = make(map[string]*AbiDecoder)
+	for _, source := range config.getSources() {
+		sourceDecoder := decoder
+		if source.abi != &config.abi {
+			if sourceDecoder, err = newAbiDecoder(source.abi, log); err != nil {
+				return nil, fmt.Errorf("source %q: %s", source.name, err)
+			}
+		}
+
+		if source.filter.actName != nil {
+			withAction := *sourceDecoder
+			withAction.actionName = *source.filter.actName
+			sourceDecoder = &withAction
+		}
+		decoder.sources[source.name] = sourceDecoder
+	}
+
+	return decoder, nil
+}
+
+// source returns the decoder of the source
+func (a *AbiDecoder) source(name string) (*AbiDecoder, error) {
+	if a.sources == nil && name == defaultSourceName {
+		return a, nil
+	}
+
+	if decoder, ok := a.sources[name]; ok {
+		return decoder, nil
+	}
+	return nil, fmt.Errorf("no decoder of source %q", name)
+}
+
 func (a *AbiDecoder) decodeEvent(data []byte) (*RawEvent, error) {
-	decodeBytes, err := a.main.decodeAction(data, defaultContractActionName)
+	decodeBytes, err := a.main.decodeAction(data, a.actionName)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := newRawEvent(decodeBytes)
 	if err != nil {
+		a.log.Error("parse contract fields error", zap.Error(err))
 		return nil, err
 	}
-	return newRawEvent(decodeBytes)
+
+	return raw, nil
 }
 
 func (a *AbiDecoder) decodeEventData(event int, data []byte) ([]byte, error) {
@@ -91,15 +204,32 @@ func (a *AbiDecoder) decodeEventData(event int, data []byte) ([]byte, error) {
 	return a.events[event].decodeStruct(data, defaultEventStructName)
 }
 
+// decodeError is the error of Decode with the event type label of the decode errors metric
+type decodeError struct {
+	eventType string
+	err       error
+}
+
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+// observeDecodeError counts the decode errors, nil metrics are not observed
+func observeDecodeError(observer *metrics.Metrics, err error) {
+	if decodeErr, ok := err.(*decodeError); ok && observer != nil {
+		observer.DecodeErrorsTotal.WithLabelValues(decodeErr.eventType).Inc()
+	}
+}
+
 func (a *AbiDecoder) Decode(data []byte) (*Event, error) {
 	raw, err := a.decodeEvent(data)
 	if err != nil {
-		return nil, err
+		return nil, &decodeError{"unknown", err}
 	}
 
 	decodeBytes, err := a.decodeEventData(raw.EventType, raw.Data)
 	if err != nil {
-		return nil, err
+		return nil, &decodeError{strconv.Itoa(raw.EventType), err}
 	}
 
 	return raw.ToEvent(decodeBytes)
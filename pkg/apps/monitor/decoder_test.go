@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -18,7 +19,7 @@ type TestEventData struct {
 }
 
 func TestDecodeAction(t *testing.T) {
-	decoder, err := newDecoder(defaultContractABI)
+	decoder, err := newDecoder(defaultContractABI, testLoggers.Decoder)
 	require.NoError(t, err)
 
 	data := []byte(`{"data":"","event_type":4,"req_id":3,"game_id":2,"casino_id":1,"sender":"test"}`)
@@ -43,7 +44,7 @@ func createStructData(t *testing.T, a uint64, b uint32, c string) []byte {
 }
 
 func TestDecodeStruct(t *testing.T) {
-	decoder, err := newDecoder(defaultEventABI)
+	decoder, err := newDecoder(defaultEventABI, testLoggers.Decoder)
 	require.NoError(t, err)
 
 	data := createStructData(t, 1, 2, "test_string")
@@ -62,7 +63,7 @@ func TestDecodeStruct(t *testing.T) {
 
 func TestAbiDecoder(t *testing.T) {
 	config := newConfig()
-	decoder, err := newAbiDecoder(&config.abi)
+	decoder, err := newAbiDecoder(&config.abi, testLoggers.Decoder)
 	require.NoError(t, err)
 
 	data := createStructData(t, 1, 2, "test_string")
@@ -81,3 +82,18 @@ func TestAbiDecoder(t *testing.T) {
 	assert.Equal(t, uint64(516516516), event.RequestID)
 	assert.Equal(t, 0, event.EventType)
 }
+
+func TestAbiDecoderErrorsMetric(t *testing.T) {
+	monitor := newTestMonitor(t)
+	decoder := monitor.abiDecoder
+	before := testutil.ToFloat64(monitor.metrics.DecodeErrorsTotal.WithLabelValues("7"))
+
+	actionJson := `{"sender":"test","casino_id":1,"game_id":2,"req_id":3,"event_type":7,"data":""}`
+	encodeBytes, err := decoder.main.abi.EncodeAction(eos.ActionName(defaultContractActionName), []byte(actionJson))
+	require.NoError(t, err)
+
+	_, err = decoder.Decode(encodeBytes)
+	require.Error(t, err)
+	observeDecodeError(monitor.metrics, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(monitor.metrics.DecodeErrorsTotal.WithLabelValues("7")))
+}
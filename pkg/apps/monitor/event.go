@@ -4,7 +4,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"go.uber.org/zap"
+	"github.com/DaoCasino/platform-action-monitor/pkg/protocol"
 	"strconv"
 	"strings"
 )
@@ -41,15 +41,7 @@ type RawEvent struct {
 	Data      EventDataSlice `json:"data"`
 }
 
-type Event struct {
-	Offset    uint64          `json:"offset"`
-	Sender    string          `json:"sender"`
-	CasinoID  uint64          `json:"casino_id"`
-	GameID    uint64          `json:"game_id"`
-	RequestID uint64          `json:"req_id"`
-	EventType int             `json:"event_type"`
-	Data      json.RawMessage `json:"data"`
-}
+type Event = protocol.Event
 
 func conv(name string, v interface{}) (uint64, error) {
 	var result uint64
@@ -104,7 +96,6 @@ func (src *RawEvent) ToEvent(data json.RawMessage) (*Event, error) {
 func newRawEvent(data []byte) (*RawEvent, error) {
 	fields := new(RawEvent)
 	if err := json.Unmarshal(data, fields); err != nil {
-		decoderLog.Error("parse contract fields error", zap.Error(err))
 		return nil, err
 	}
 
@@ -141,13 +132,14 @@ func filterEventsByEventTypes(events []*Event, eventTypes []int) []*Event {
 	return result
 }
 
+// filterEventsFromOffset keeps the events after the offset and the synthetic events, they have no offset
 func filterEventsFromOffset(events []*Event, offset uint64) []*Event {
-	for index, event := range events {
+	result := events[:0]
+	for _, event := range events {
 		event := event
-		if event.Offset > offset {
-			return events[index:]
+		if event.IsSynthetic() || event.Offset > offset {
+			result = append(result, event)
 		}
 	}
-
-	return events[:0]
+	return result
 }
@@ -0,0 +1,70 @@
+package monitor
+
+import "github.com/DaoCasino/platform-action-monitor/pkg/protocol"
+
+// Platform event types, see configs/abi/events
+const (
+	EventGameStarted           = protocol.EventGameStarted
+	EventActionRequest         = protocol.EventActionRequest
+	EventSignidicePart1Request = protocol.EventSignidicePart1Request
+	EventSignidicePart2Request = protocol.EventSignidicePart2Request
+	EventGameFinished          = protocol.EventGameFinished
+	EventGameFailed            = protocol.EventGameFailed
+	EventGameMessage           = protocol.EventGameMessage
+)
+
+// Events generated by the monitor itself have negative types, they are not stored in the chain
+const (
+	EventAlertStuckGame = protocol.EventAlertStuckGame
+)
+
+const topicAlertStuckGame = "alert_stuck_game"
+
+// names of the event types, the ABI files in configs/abi/events
+var eventTypeNames = map[int]string{
+	EventGameStarted:           "game_started",
+	EventActionRequest:         "action_request",
+	EventSignidicePart1Request: "signidice_part_1_request",
+	EventSignidicePart2Request: "signidice_part_2_request",
+	EventGameFinished:          "game_finished",
+	EventGameFailed:            "game_failed",
+	EventGameMessage:           "game_message",
+	EventAlertStuckGame:        topicAlertStuckGame,
+}
+
+// EventTypeName returns the name of the event type, eg game_finished, or event_N for the unknown types
+func EventTypeName(eventType int) string {
+	if name, ok := eventTypeNames[eventType]; ok {
+		return name
+	}
+	return eventTopicName(eventType)
+}
+
+// ResolveTopic returns the topic of the event type name, eg casino.game_finished is casino.event_4,
+// the other topics are returned as is
+func ResolveTopic(topic string) string {
+	source, name := parseTopic(topic)
+	for eventType, eventName := range eventTypeNames {
+		if eventName == name && eventType >= 0 {
+			return topicName(source, eventTopicName(eventType))
+		}
+	}
+	return topic
+}
+
+// the event data structs, Event.Payload decodes Data to them
+type (
+	GameStartedData           = protocol.GameStartedData
+	ActionRequestData         = protocol.ActionRequestData
+	SignidicePart1RequestData = protocol.SignidicePart1RequestData
+	SignidicePart2RequestData = protocol.SignidicePart2RequestData
+	GameFinishedData          = protocol.GameFinishedData
+	GameFailedData            = protocol.GameFailedData
+	GameMessageData           = protocol.GameMessageData
+	StuckGameAlertData        = protocol.StuckGameAlertData
+)
+
+func isSyntheticTopic(topic string) bool {
+	_, name := parseTopic(topic)
+	return name == topicAlertStuckGame
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testEventDataABI = map[int]string{
+	EventGameStarted:           "game_started.abi",
+	EventActionRequest:         "action_request.abi",
+	EventSignidicePart1Request: "signidice_part_1_request.abi",
+	EventSignidicePart2Request: "signidice_part_2_request.abi",
+	EventGameFinished:          "game_finished.abi",
+	EventGameFailed:            "game_failed.abi",
+	EventGameMessage:           "game_message.abi",
+}
+
+var testAbiTypes = map[reflect.Type]string{
+	reflect.TypeOf(uint8(0)):          "uint8",
+	reflect.TypeOf(false):             "bool",
+	reflect.TypeOf(eos.Checksum256{}): "checksum256",
+	reflect.TypeOf(eos.Asset{}):       "asset",
+	reflect.TypeOf(eos.HexBytes{}):    "bytes",
+}
+
+func TestEventDataMatchABI(t *testing.T) {
+	for eventType, filename := range testEventDataABI {
+		t.Run(filename, func(t *testing.T) {
+			decoder, err := newDecoder("../../../configs/abi/events/"+filename, testLoggers.Decoder)
+			require.NoError(t, err)
+
+			abiStruct := decoder.abi.StructForName(defaultEventStructName)
+			require.NotNil(t, abiStruct)
+
+			data, err := (&Event{EventType: eventType}).Payload()
+			require.NoError(t, err)
+
+			dataType := reflect.TypeOf(data).Elem()
+			require.Equal(t, len(abiStruct.Fields), dataType.NumField())
+
+			for i, field := range abiStruct.Fields {
+				goField := dataType.Field(i)
+				assert.Equal(t, field.Name, strings.Split(goField.Tag.Get("json"), ",")[0])
+				assert.Equal(t, field.Type, testAbiTypes[goField.Type], "field %s", field.Name)
+			}
+		})
+	}
+}
+
+func TestEventPayload(t *testing.T) {
+	asset, err := eos.NewAssetFromString("1.5000 BET")
+	require.NoError(t, err)
+	digest := eos.Checksum256(bytes.Repeat([]byte{0xab}, 32))
+
+	cases := []struct {
+		eventType int
+		data      interface{}
+	}{
+		{EventGameStarted, &GameStartedData{}},
+		{EventActionRequest, &ActionRequestData{ActionType: 3, NeedDeposit: true}},
+		{EventSignidicePart1Request, &SignidicePart1RequestData{Digest: digest}},
+		{EventSignidicePart2Request, &SignidicePart2RequestData{Digest: digest}},
+		{EventGameFinished, &GameFinishedData{PlayerWinAmount: asset, Msg: eos.HexBytes{1, 2, 3}}},
+		{EventGameFailed, &GameFailedData{PlayerWinAmount: asset, Msg: eos.HexBytes{}}},
+		{EventGameMessage, &GameMessageData{Msg: eos.HexBytes{4, 5}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprint(tc.eventType), func(t *testing.T) {
+			decoder, err := newDecoder("../../../configs/abi/events/"+testEventDataABI[tc.eventType], testLoggers.Decoder)
+			require.NoError(t, err)
+
+			var buffer bytes.Buffer
+			require.NoError(t, eos.NewEncoder(&buffer).Encode(tc.data))
+
+			decodeBytes, err := decoder.decodeStruct(buffer.Bytes(), defaultEventStructName)
+			require.NoError(t, err)
+
+			event := &Event{EventType: tc.eventType, Data: decodeBytes}
+			payload, err := event.Payload()
+			require.NoError(t, err)
+			assert.Equal(t, tc.data, payload)
+		})
+	}
+}
+
+func TestEventPayloadUnknownType(t *testing.T) {
+	event := &Event{EventType: 100}
+	_, err := event.Payload()
+	require.Error(t, err)
+}
+
+func TestResolveTopic(t *testing.T) {
+	assert.Equal(t, "event_4", ResolveTopic("game_finished"))
+	assert.Equal(t, "casino.event_0", ResolveTopic("casino.game_started"))
+	assert.Equal(t, "event_1", ResolveTopic("event_1"))
+	assert.Equal(t, "casino.alert_stuck_game", ResolveTopic("casino.alert_stuck_game"))
+
+	assert.Equal(t, "game_message", EventTypeName(EventGameMessage))
+	assert.Equal(t, topicAlertStuckGame, EventTypeName(EventAlertStuckGame))
+	assert.Equal(t, "event_10", EventTypeName(10))
+}
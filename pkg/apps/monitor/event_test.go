@@ -1,9 +1,11 @@
 package monitor
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestNewEvent(t *testing.T) {
@@ -53,4 +55,17 @@ func TestFilterEventsFromOffset(t *testing.T) {
 	result := filterEventsFromOffset(events, 2)
 	assert.Equal(t, 1, len(result))
 	assert.Equal(t, uint64(3), result[0].Offset)
+
+	// the synthetic events have no offset and are kept
+	events = []*Event{{Offset: 1}, {EventType: EventAlertStuckGame}, {Offset: 3}}
+	result = filterEventsFromOffset(events, 2)
+	require.Equal(t, 2, len(result))
+	assert.True(t, result[0].IsSynthetic())
+}
+
+func TestEventBlockTimeJSON(t *testing.T) {
+	event := &Event{Offset: 1, BlockTime: time.Date(2020, 5, 1, 12, 0, 0, 500000000, time.UTC)}
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"block_time":"2020-05-01T12:00:00.5Z"`)
 }
@@ -0,0 +1,228 @@
+package monitor
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+
+	// actions fetched by one query of the export
+	defaultExportPageSize = 1000
+)
+
+var exportCSVHeader = []string{"offset", "block_time", "source", "topic", "event_type", "event_name", "casino_id", "game_id", "req_id", "sender", "data"}
+
+// ExportRequest selects the exported events, zero values are not limited
+type ExportRequest struct {
+	FromOffset uint64
+	// inclusive
+	ToOffset uint64
+	// block time range [From, To)
+	From time.Time
+	To   time.Time
+	// topics of the events, eg event_4 or casino.game_finished, empty exports all events
+	Topics   []string
+	Format   string
+	PageSize uint
+}
+
+// ExportStats is the result of the export, NextOffset continues the export after the last fetched action
+type ExportStats struct {
+	Events     int
+	Skipped    int
+	NextOffset uint64
+}
+
+type eventWriter interface {
+	write(event *Event) error
+	flush() error
+}
+
+type jsonEventWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonEventWriter) write(event *Event) error {
+	return w.encoder.Encode(event)
+}
+
+func (w *jsonEventWriter) flush() error {
+	return nil
+}
+
+type csvEventWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvEventWriter) write(event *Event) error {
+	return w.writer.Write([]string{
+		strconv.FormatUint(event.Offset, 10),
+		event.BlockTime.UTC().Format(time.RFC3339),
+		event.Source,
+		eventTopic(event),
+		strconv.Itoa(event.EventType),
+		EventTypeName(event.EventType),
+		strconv.FormatUint(event.CasinoID, 10),
+		strconv.FormatUint(event.GameID, 10),
+		strconv.FormatUint(event.RequestID, 10),
+		event.Sender,
+		string(event.Data),
+	})
+}
+
+func (w *csvEventWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// flusher is implemented by http.ResponseWriter
+type flusher interface {
+	Flush()
+}
+
+func newEventWriter(w io.Writer, format string) (eventWriter, error) {
+	switch format {
+	case ExportFormatJSON:
+		return &jsonEventWriter{json.NewEncoder(w)}, nil
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportCSVHeader); err != nil {
+			return nil, err
+		}
+		return &csvEventWriter{writer}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func (r *ExportRequest) validate() error {
+	if r.Format != ExportFormatJSON && r.Format != ExportFormatCSV {
+		return fmt.Errorf("unknown export format %q", r.Format)
+	}
+	if r.ToOffset != 0 && r.ToOffset < r.FromOffset {
+		return fmt.Errorf("to offset %d is less than from offset %d", r.ToOffset, r.FromOffset)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.To.After(r.From) {
+		return fmt.Errorf("to %s is not after from %s", r.To.Format(time.RFC3339), r.From.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// parseExportQuery parses format, from_offset, to_offset, from, to (RFC3339) and comma separated topics
+func parseExportQuery(query url.Values) (*ExportRequest, error) {
+	request := &ExportRequest{Format: ExportFormatJSON}
+	if format := query.Get("format"); format != "" {
+		request.Format = format
+	}
+
+	var err error
+	if value := query.Get("from_offset"); value != "" {
+		if request.FromOffset, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("from_offset: %s", err)
+		}
+	}
+	if value := query.Get("to_offset"); value != "" {
+		if request.ToOffset, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("to_offset: %s", err)
+		}
+	}
+	if value := query.Get("from"); value != "" {
+		if request.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("from: %s", err)
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if request.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("to: %s", err)
+		}
+	}
+	if value := query.Get("topics"); value != "" {
+		request.Topics = strings.Split(value, ",")
+	}
+
+	return request, request.validate()
+}
+
+// Export writes the events of the request page by page as JSON Lines or CSV. The events are fetched and decoded
+// like fetchAllEvents without the eventExpires cutoff, the actions failed to decode are skipped.
+func Export(ctx context.Context, db DatabaseConnect, config *Config, decoder *AbiDecoder, w io.Writer, request *ExportRequest) (*ExportStats, error) {
+	return export(ctx, db, nil, config, decoder, w, request)
+}
+
+// export observes the queries and the decode errors with the metrics of the monitor
+func export(ctx context.Context, db DatabaseConnect, observer *metrics.Metrics, config *Config, decoder *AbiDecoder, w io.Writer, request *ExportRequest) (*ExportStats, error) {
+	stats := &ExportStats{NextOffset: request.FromOffset}
+	if err := request.validate(); err != nil {
+		return stats, err
+	}
+
+	writer, err := newEventWriter(w, request.Format)
+	if err != nil {
+		return stats, err
+	}
+
+	var topics map[string]bool
+	if len(request.Topics) != 0 {
+		topics = make(map[string]bool, len(request.Topics))
+		for _, topic := range request.Topics {
+			topics[ResolveTopic(topic)] = true
+		}
+	}
+
+	pageSize := request.PageSize
+	if pageSize == 0 {
+		pageSize = defaultExportPageSize
+	}
+
+	sources := config.getSources()
+	filters := sourceFilters(sources)
+
+	for {
+		dataset, err := fetchActionDataRange(ctx, db, observer, stats.NextOffset, request.ToOffset, request.From, request.To, pageSize, filters)
+		if err != nil {
+			return stats, err
+		}
+
+		for _, data := range dataset {
+			event, err := decoder.decodeRows(sources, data)
+			observeDecodeError(observer, err)
+			if err != nil {
+				stats.Skipped++
+				continue
+			}
+			if topics != nil && !topics[eventTopic(event)] {
+				continue
+			}
+
+			if err := writer.write(event); err != nil {
+				return stats, err
+			}
+			stats.Events++
+		}
+
+		if err := writer.flush(); err != nil {
+			return stats, err
+		}
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+
+		if len(dataset) != 0 {
+			stats.NextOffset = dataset[len(dataset)-1].offset + 1
+		}
+		if uint(len(dataset)) < pageSize || (request.ToOffset != 0 && stats.NextOffset > request.ToOffset) {
+			return stats, nil
+		}
+	}
+}
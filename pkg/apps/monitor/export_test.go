@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	exportFromOffsetRegexp = regexp.MustCompile(`receipt_global_sequence >=\$(\d+)`)
+	exportToOffsetRegexp   = regexp.MustCompile(`receipt_global_sequence <=\$(\d+)`)
+	exportLimitRegexp      = regexp.MustCompile(`LIMIT \$(\d+)`)
+)
+
+// exportDatabase pages through the actions like the query of fetchActionDataRange
+type exportDatabase struct {
+	DatabaseMock
+	actions []*ActionTraceRows
+	queries int
+}
+
+type exportRows struct {
+	DatabaseMockRows
+	actions []*ActionTraceRows
+	current *ActionTraceRows
+}
+
+func (r *exportRows) Next() bool {
+	if len(r.actions) == 0 {
+		return false
+	}
+	r.current, r.actions = r.actions[0], r.actions[1:]
+	return true
+}
+
+func (r *exportRows) Scan(dest ...interface{}) error {
+	*dest[0].(*[]byte) = r.current.actData
+	*dest[1].(*uint64) = r.current.offset
+	*dest[2].(*time.Time) = r.current.blockTime
+	*dest[3].(*string) = r.current.actAccount
+	*dest[4].(*string) = r.current.actName
+	return nil
+}
+
+func (r *exportRows) Err() error {
+	return nil
+}
+
+func exportArg(sql string, re *regexp.Regexp, args []interface{}) (interface{}, bool) {
+	match := re.FindStringSubmatch(sql)
+	if match == nil {
+		return nil, false
+	}
+	index, _ := strconv.Atoi(match[1])
+	return args[index-1], true
+}
+
+func (db *exportDatabase) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.queries++
+	from, _ := exportArg(sql, exportFromOffsetRegexp, args)
+	to, hasTo := exportArg(sql, exportToOffsetRegexp, args)
+	limit, _ := exportArg(sql, exportLimitRegexp, args)
+
+	rows := &exportRows{}
+	for _, action := range db.actions {
+		if action.offset < from.(uint64) || (hasTo && action.offset > to.(uint64)) {
+			continue
+		}
+		if uint(len(rows.actions)) == limit.(uint) {
+			break
+		}
+		rows.actions = append(rows.actions, action)
+	}
+	return rows, nil
+}
+
+func newExportDatabase(t *testing.T, decoder *AbiDecoder, count int) *exportDatabase {
+	db := &exportDatabase{}
+	blockTime := time.Date(2020, 10, 19, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < count; i++ {
+		db.actions = append(db.actions, &ActionTraceRows{
+			actData:    encodeTestAction(t, decoder, createStructData(t, 1, 2, "test_string")),
+			offset:     uint64(10 + i),
+			blockTime:  blockTime.Add(time.Duration(i) * time.Second),
+			actAccount: "casino",
+			actName:    defaultContractActionName,
+		})
+	}
+	// not decoded
+	db.actions[1].actData = db.actions[1].actData[:3]
+	return db
+}
+
+func TestExportJSON(t *testing.T) {
+	monitor := newTestMonitor(t)
+	db := newExportDatabase(t, monitor.abiDecoder, 5)
+
+	buffer := new(bytes.Buffer)
+	request := &ExportRequest{FromOffset: 10, Format: ExportFormatJSON, PageSize: 2}
+	stats, err := Export(context.Background(), db, monitor.config, monitor.abiDecoder, buffer, request)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Events)
+	assert.Equal(t, 1, stats.Skipped)
+	assert.Equal(t, uint64(15), stats.NextOffset)
+	assert.Equal(t, 3, db.queries)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 4)
+	event := new(Event)
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), event))
+	assert.Equal(t, uint64(12), event.Offset)
+	assert.Equal(t, uint64(2), event.GameID)
+	assert.JSONEq(t, `{"a":1,"b":2,"c":"test_string"}`, string(event.Data))
+
+	// the last page ends at the to offset
+	db.queries = 0
+	request = &ExportRequest{FromOffset: 12, ToOffset: 13, Format: ExportFormatJSON, PageSize: 2, Topics: []string{"game_started"}}
+	stats, err = Export(context.Background(), db, monitor.config, monitor.abiDecoder, new(bytes.Buffer), request)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Events)
+	assert.Equal(t, uint64(14), stats.NextOffset)
+	assert.Equal(t, 1, db.queries)
+
+	request.Topics = []string{"event_1"}
+	stats, err = Export(context.Background(), db, monitor.config, monitor.abiDecoder, new(bytes.Buffer), request)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Events)
+}
+
+func TestExportCSV(t *testing.T) {
+	monitor := newTestMonitor(t)
+	db := newExportDatabase(t, monitor.abiDecoder, 3)
+
+	buffer := new(bytes.Buffer)
+	stats, err := Export(context.Background(), db, monitor.config, monitor.abiDecoder, buffer, &ExportRequest{Format: ExportFormatCSV})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Events)
+
+	records, err := csv.NewReader(buffer).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, exportCSVHeader, records[0])
+	assert.Equal(t, []string{"10", "2020-10-19T12:00:00Z", "", "event_0", "0", "game_started", "1", "2", "3", "test"}, records[1][:10])
+	assert.JSONEq(t, `{"a":1,"b":2,"c":"test_string"}`, records[1][10])
+}
+
+func TestParseExportQuery(t *testing.T) {
+	query, _ := url.ParseQuery("format=csv&from_offset=10&to_offset=20&from=2020-10-19T00:00:00Z&to=2020-10-20T00:00:00Z&topics=event_0,casino.game_finished")
+	request, err := parseExportQuery(query)
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatCSV, request.Format)
+	assert.Equal(t, uint64(10), request.FromOffset)
+	assert.Equal(t, uint64(20), request.ToOffset)
+	assert.Equal(t, time.Date(2020, 10, 19, 0, 0, 0, 0, time.UTC), request.From)
+	assert.Equal(t, []string{"event_0", "casino.game_finished"}, request.Topics)
+
+	request, err = parseExportQuery(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatJSON, request.Format)
+
+	for _, query := range []string{"format=xml", "from_offset=a", "from=yesterday", "from_offset=20&to_offset=10", "from=2020-10-20T00:00:00Z&to=2020-10-19T00:00:00Z"} {
+		values, _ := url.ParseQuery(query)
+		_, err := parseExportQuery(values)
+		assert.Error(t, err, query)
+	}
+}
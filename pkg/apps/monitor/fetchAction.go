@@ -3,13 +3,18 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
 	// "github.com/jackc/pgx/v4"
 	"strings"
+	"time"
 )
 
 type ActionTraceRows struct {
-	actData []byte
-	offset  uint64
+	actData    []byte
+	offset     uint64
+	blockTime  time.Time
+	actAccount string
+	actName    string
 }
 
 type SqlQuery struct {
@@ -18,30 +23,68 @@ type SqlQuery struct {
 }
 
 const (
-	sqlFetchAction       = "SELECT action_trace.act_data, action_trace.receipt_global_sequence AS offset FROM chain.action_trace WHERE %s ORDER BY action_trace.receipt_global_sequence ASC"
-	sqlFetchActions      = "SELECT action_trace.act_data, action_trace.receipt_global_sequence AS offset FROM chain.action_trace INNER JOIN chain.block_info ON block_info.block_num = action_trace.block_num WHERE %s ORDER BY action_trace.receipt_global_sequence ASC"
+	sqlFetchAction       = "SELECT action_trace.act_data, action_trace.receipt_global_sequence AS offset, block_info.timestamp, action_trace.act_account, action_trace.act_name FROM chain.action_trace LEFT JOIN chain.block_info ON block_info.block_num = action_trace.block_num WHERE %s ORDER BY action_trace.receipt_global_sequence ASC"
+	sqlFetchActions      = "SELECT action_trace.act_data, action_trace.receipt_global_sequence AS offset, block_info.timestamp, action_trace.act_account, action_trace.act_name FROM chain.action_trace INNER JOIN chain.block_info ON block_info.block_num = action_trace.block_num WHERE %s ORDER BY action_trace.receipt_global_sequence ASC"
+	sqlFetchLastAction   = "SELECT block_info.timestamp FROM chain.action_trace INNER JOIN chain.block_info ON block_info.block_num = action_trace.block_num %s ORDER BY action_trace.receipt_global_sequence DESC LIMIT 1"
 	sqlWhereEventExpires = "block_info.timestamp > now() - interval '%s'"
+	sqlWhereOffset       = "action_trace.receipt_global_sequence ="
+	sqlWhereFromOffset   = "action_trace.receipt_global_sequence >="
+	sqlWhereToOffset     = "action_trace.receipt_global_sequence <="
+	sqlWhereFromTime     = "block_info.timestamp >="
+	sqlWhereToTime       = "block_info.timestamp <"
 	sqlWhereActAccount   = "action_trace.act_account="
 	sqlWhereActName      = "action_trace.act_name="
 	sqlWhereAnd          = " AND "
+	sqlWhereOr           = " OR "
 )
 
-func newSqlQuery(filter *DatabaseFilters) *SqlQuery {
+// Query label of the query duration metric
+const (
+	queryFetchAction  = "fetch_action"
+	queryFetchActions = "fetch_actions"
+	queryCheckToken   = "check_token"
+	queryLastAction   = "last_action"
+	queryExportAction = "export_actions"
+)
+
+// newSqlQuery matches the actions of any filter
+func newSqlQuery(filters []DatabaseFilters) *SqlQuery {
 	s := &SqlQuery{make([]string, 0), make([]interface{}, 0)}
 
-	if filter.actAccount != nil {
-		s.append(sqlWhereActAccount, *filter.actAccount)
+	conditions := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		keys := make([]string, 0, 2)
+		if filter.actAccount != nil {
+			keys = append(keys, s.placeholder(sqlWhereActAccount, *filter.actAccount))
+		}
+		if filter.actName != nil {
+			keys = append(keys, s.placeholder(sqlWhereActName, *filter.actName))
+		}
+
+		if len(keys) == 0 { // the filter matches all actions
+			return &SqlQuery{make([]string, 0), make([]interface{}, 0)}
+		}
+		conditions = append(conditions, strings.Join(keys, sqlWhereAnd))
 	}
-	if filter.actName != nil {
-		s.append(sqlWhereActName, *filter.actName)
+
+	switch len(conditions) {
+	case 0:
+	case 1:
+		s.key = append(s.key, conditions[0])
+	default:
+		s.key = append(s.key, "(("+strings.Join(conditions, ")"+sqlWhereOr+"(")+"))")
 	}
 
 	return s
 }
 
-func (s *SqlQuery) append(key string, value interface{}) {
+func (s *SqlQuery) placeholder(key string, value interface{}) string {
 	s.value = append(s.value, value)
-	s.key = append(s.key, fmt.Sprintf("%s$%d", key, len(s.value)))
+	return fmt.Sprintf("%s$%d", key, len(s.value))
+}
+
+func (s *SqlQuery) append(key string, value interface{}) {
+	s.key = append(s.key, s.placeholder(key, value))
 }
 
 func (s *SqlQuery) getRow() (string, []interface{}) {
@@ -61,35 +104,70 @@ func (s *SqlQuery) getRows(eventExpires *string) (string, []interface{}) {
 	return sql, s.value
 }
 
-func fetchActionData(ctx context.Context, db DatabaseConnect, offset uint64, filter *DatabaseFilters) (*ActionTraceRows, error) {
-	s := newSqlQuery(filter)
-	s.append("action_trace.receipt_global_sequence =", offset)
+func fetchActionData(ctx context.Context, db DatabaseConnect, observer *metrics.Metrics, offset uint64, filters []DatabaseFilters) (*ActionTraceRows, error) {
+	s := newSqlQuery(filters)
+	s.append(sqlWhereOffset, offset)
 
 	sql, args := s.getRow()
 	rows := new(ActionTraceRows)
+	defer observer.ObserveQuery(queryFetchAction, time.Now())
 
-	err := db.QueryRow(ctx, sql, args...).Scan(&rows.actData, &rows.offset)
+	// block info may be not inserted yet
+	var blockTime *time.Time
+	err := db.QueryRow(ctx, sql, args...).Scan(&rows.actData, &rows.offset, &blockTime, &rows.actAccount, &rows.actName)
+	if blockTime != nil {
+		rows.blockTime = *blockTime
+	}
 	return rows, err
 }
 
-func fetchAllActionData(ctx context.Context, db DatabaseConnect, offset uint64, count uint, eventExpires *string, filter *DatabaseFilters) ([]*ActionTraceRows, error) {
-	s := newSqlQuery(filter)
-	s.append("action_trace.receipt_global_sequence >=", offset)
+func fetchAllActionData(ctx context.Context, db DatabaseConnect, observer *metrics.Metrics, offset uint64, count uint, eventExpires *string, filters []DatabaseFilters) ([]*ActionTraceRows, error) {
+	s := newSqlQuery(filters)
+	s.append(sqlWhereFromOffset, offset)
 	sql, args := s.getRows(eventExpires)
 
+	defer observer.ObserveQuery(queryFetchActions, time.Now())
+	return queryActionData(ctx, db, sql, args, count)
+}
+
+// fetchActionDataRange fetches count actions from offset to toOffset inclusive and in the block time range [from, to),
+// zero toOffset, from and to are not limited
+func fetchActionDataRange(ctx context.Context, db DatabaseConnect, observer *metrics.Metrics, offset uint64, toOffset uint64, from time.Time, to time.Time, count uint, filters []DatabaseFilters) ([]*ActionTraceRows, error) {
+	s := newSqlQuery(filters)
+	s.append(sqlWhereFromOffset, offset)
+	if toOffset != 0 {
+		s.append(sqlWhereToOffset, toOffset)
+	}
+	// block_info.timestamp is timestamp without time zone in UTC
+	if !from.IsZero() {
+		s.append(sqlWhereFromTime, from.UTC())
+	}
+	if !to.IsZero() {
+		s.append(sqlWhereToTime, to.UTC())
+	}
+	sql, args := s.getRows(nil)
+
+	defer observer.ObserveQuery(queryExportAction, time.Now())
+	return queryActionData(ctx, db, sql, args, count)
+}
+
+func queryActionData(ctx context.Context, db DatabaseConnect, sql string, args []interface{}, count uint) ([]*ActionTraceRows, error) {
 	if count != 0 {
 		args = append(args, count)
 		sql += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
 
-	rows, _ := db.Query(ctx, sql, args...)
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	result := make([]*ActionTraceRows, 0, count)
 
 	for rows.Next() {
 		data := new(ActionTraceRows)
-		err := rows.Scan(&data.actData, &data.offset)
+		err := rows.Scan(&data.actData, &data.offset, &data.blockTime, &data.actAccount, &data.actName)
 		if err != nil {
 			return nil, err
 		}
@@ -99,3 +177,18 @@ func fetchAllActionData(ctx context.Context, db DatabaseConnect, offset uint64,
 
 	return result, rows.Err()
 }
+
+// fetchLastActionTime returns the block time of the last action matching the filters
+func fetchLastActionTime(ctx context.Context, db DatabaseConnect, observer *metrics.Metrics, filters []DatabaseFilters) (time.Time, error) {
+	s := newSqlQuery(filters)
+	where := ""
+	if len(s.key) != 0 {
+		where = "WHERE " + strings.Join(s.key, sqlWhereAnd)
+	}
+
+	defer observer.ObserveQuery(queryLastAction, time.Now())
+
+	var blockTime time.Time
+	err := db.QueryRow(ctx, fmt.Sprintf(sqlFetchLastAction, where), s.value...).Scan(&blockTime)
+	return blockTime, err
+}
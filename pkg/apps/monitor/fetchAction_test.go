@@ -4,7 +4,9 @@ import (
 	"context"
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestFetchActionData(t *testing.T) {
@@ -15,7 +17,7 @@ func TestFetchActionData(t *testing.T) {
 	config.db.filter.actName = &testFilter
 	config.db.filter.actAccount = &testFilter
 
-	_, err := fetchActionData(context.Background(), mock, 0, &config.db.filter)
+	_, err := fetchActionData(context.Background(), mock, nil, 0, []DatabaseFilters{config.db.filter})
 	switch err {
 	case pgx.ErrNoRows:
 	default:
@@ -33,7 +35,80 @@ func TestFetchAllActionData(t *testing.T) {
 	config.db.filter.actName = &testFilter
 	config.db.filter.actAccount = &testFilter
 
-	result, _ = fetchAllActionData(context.Background(), mock, 0, 1, &config.eventExpires, &config.db.filter)
+	result, _ = fetchAllActionData(context.Background(), mock, nil, 0, 1, &config.eventExpires, []DatabaseFilters{config.db.filter})
 	// require.NoError(t, err)
 	assert.Equal(t, len(result), 0)
 }
+
+func TestFetchActionDataMemory(t *testing.T) {
+	monitor := newTestMonitor(t)
+	db := newMemoryDatabase(t, monitor.abiDecoder)
+	ctx := context.Background()
+
+	rows, err := fetchActionData(ctx, db, nil, 11, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), rows.offset)
+	assert.Equal(t, testBlockTime.Add(time.Second), rows.blockTime)
+	assert.Equal(t, "casino", rows.actAccount)
+
+	// the block is not inserted yet
+	rows, err = fetchActionData(ctx, db, nil, 14, nil)
+	require.NoError(t, err)
+	assert.True(t, rows.blockTime.IsZero())
+
+	_, err = fetchActionData(ctx, db, nil, 15, nil)
+	assert.Equal(t, pgx.ErrNoRows, err)
+
+	other, casino, send := "other", "casino", defaultContractActionName
+	_, err = fetchActionData(ctx, db, nil, 11, []DatabaseFilters{{actAccount: &other}})
+	assert.Equal(t, pgx.ErrNoRows, err)
+
+	filters := []DatabaseFilters{{actAccount: &other}, {actAccount: &casino, actName: &send}}
+	_, err = fetchActionData(ctx, db, nil, 11, filters)
+	assert.NoError(t, err)
+}
+
+func TestFetchAllActionDataMemory(t *testing.T) {
+	monitor := newTestMonitor(t)
+	db := newMemoryDatabase(t, monitor.abiDecoder)
+	ctx := context.Background()
+
+	offsets := func(result []*ActionTraceRows) []uint64 {
+		offsets := make([]uint64, 0, len(result))
+		for _, rows := range result {
+			offsets = append(offsets, rows.offset)
+		}
+		return offsets
+	}
+
+	// the action without the block is skipped
+	result, err := fetchAllActionData(ctx, db, nil, 11, 0, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{11, 12, 13}, offsets(result))
+
+	result, err = fetchAllActionData(ctx, db, nil, 0, 2, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{10, 11}, offsets(result))
+
+	// the time is a minute after the first block
+	expires := "58 seconds"
+	result, err = fetchAllActionData(ctx, db, nil, 0, 0, &expires, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{13}, offsets(result))
+
+	other := "other"
+	result, err = fetchAllActionData(ctx, db, nil, 0, 0, nil, []DatabaseFilters{{actName: &other}})
+	require.NoError(t, err)
+	assert.Len(t, result, 0)
+
+	result, err = fetchActionDataRange(ctx, db, nil, 10, 13, testBlockTime.Add(time.Second), testBlockTime.Add(3*time.Second), 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{11, 12}, offsets(result))
+
+	blockTime, err := fetchLastActionTime(ctx, db, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testBlockTime.Add(3*time.Second), blockTime)
+
+	_, err = fetchLastActionTime(ctx, db, nil, []DatabaseFilters{{actName: &other}})
+	assert.Equal(t, pgx.ErrNoRows, err)
+}
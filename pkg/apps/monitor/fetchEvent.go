@@ -2,51 +2,80 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"github.com/jackc/pgx/v4"
 	"go.uber.org/zap"
 )
 
-func fetchEvent(ctx context.Context, conn DatabaseConnect, offset uint64) (*Event, error) {
-	filter := config.db.filter
-	rows, err := fetchActionData(ctx, conn, offset, &filter)
+func (m *Monitor) fetchEvent(ctx context.Context, conn DatabaseConnect, offset uint64) (*Event, error) {
+	rows, err := m.fetchAction(ctx, conn, offset)
+	if err != nil {
+		return nil, err
+	}
+	return m.decodeRows(m.config.getSources(), rows)
+}
+
+// fetchAction fetches the action of the sources
+func (m *Monitor) fetchAction(ctx context.Context, conn DatabaseConnect, offset uint64) (*ActionTraceRows, error) {
+	sources := m.config.getSources()
+	rows, err := fetchActionData(ctx, conn, m.metrics, offset, sourceFilters(sources))
 	switch err {
 	case nil:
-		// ok
-		var event *Event
-		event, err = abiDecoder.Decode(rows.actData)
-		if err == nil {
-			event.Offset = rows.offset
-			return event, nil
-		}
+		return rows, nil
 	case pgx.ErrNoRows:
-		scraperLog.Debug("no act_data with filter",
-			zap.Stringp("act_name", filter.actName),
-			zap.Stringp("act_account", filter.actAccount),
-		)
+		m.log.Scraper.Debug("no act_data with filter", zap.Int("sources", len(sources)))
 	default:
-		scraperLog.Error("handleNotify SQL error", zap.Error(err))
+		m.log.Scraper.Error("handleNotify SQL error", zap.Error(err))
 	}
 
 	return nil, err
 }
 
-func fetchAllEvents(ctx context.Context, conn DatabaseConnect, offset uint64, count uint) ([]*Event, error) {
-	filter := config.db.filter
-	eventExpires := config.eventExpires
+func (m *Monitor) fetchAllEvents(ctx context.Context, conn DatabaseConnect, offset uint64, count uint) ([]*Event, error) {
+	sources := m.config.getSources()
+	eventExpires := m.config.eventExpires
 
-	dataset, err := fetchAllActionData(ctx, conn, offset, count, &eventExpires, &filter)
+	dataset, err := fetchAllActionData(ctx, conn, m.metrics, offset, count, &eventExpires, sourceFilters(sources))
 	if err != nil {
 		return nil, err
 	}
 
 	events := make([]*Event, 0, len(dataset))
 	for _, data := range dataset {
-		data := data
-		if event, err := abiDecoder.Decode(data.actData); err == nil {
-			event.Offset = data.offset
+		if event, err := m.decodeRows(sources, data); err == nil {
 			events = append(events, event)
 		}
 	}
 
 	return events, nil
 }
+
+// decodeRows decodes the action and counts the decode errors
+func (m *Monitor) decodeRows(sources []*SourceConfig, data *ActionTraceRows) (*Event, error) {
+	event, err := m.abiDecoder.decodeRows(sources, data)
+	observeDecodeError(m.metrics, err)
+	return event, err
+}
+
+// decodeRows decodes the action with the ABI of the matching source
+func (a *AbiDecoder) decodeRows(sources []*SourceConfig, data *ActionTraceRows) (*Event, error) {
+	source, ok := sourceOf(sources, data.actAccount, data.actName)
+	if !ok {
+		return nil, fmt.Errorf("no source of action %s::%s", data.actAccount, data.actName)
+	}
+
+	decoder, err := a.source(source.name)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := decoder.Decode(data.actData)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Offset = data.offset
+	event.BlockTime = data.blockTime
+	event.Source = source.name
+	return event, nil
+}
@@ -5,28 +5,49 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestFetchEventFetch(t *testing.T) {
-	config = newConfig()
+	monitor := newTestMonitor(t)
 	db := &DatabaseMock{}
 
 	testFilter := "test"
-	config.db.filter.actName = &testFilter
-	config.db.filter.actAccount = &testFilter
+	monitor.config.db.filter.actName = &testFilter
+	monitor.config.db.filter.actAccount = &testFilter
 
-	_, err := fetchEvent(context.Background(), db, 0)
+	_, err := monitor.fetchEvent(context.Background(), db, 0)
 	require.Error(t, err)
 }
 
 func TestFetchEventFetchAll(t *testing.T) {
-	config = newConfig()
+	monitor := newTestMonitor(t)
 	db := &DatabaseMock{}
 
 	testFilter := "test"
-	config.db.filter.actName = &testFilter
-	config.db.filter.actAccount = &testFilter
+	monitor.config.db.filter.actName = &testFilter
+	monitor.config.db.filter.actAccount = &testFilter
 
-	events, _ := fetchAllEvents(context.Background(), db, 0, 1)
+	events, _ := monitor.fetchAllEvents(context.Background(), db, 0, 1)
 	assert.Equal(t, len(events), 0)
 }
+
+func TestFetchEventMemory(t *testing.T) {
+	monitor, db := newTestMemoryMonitor(t)
+
+	event, err := monitor.fetchEvent(context.Background(), db, 12)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), event.Offset)
+	assert.Equal(t, testBlockTime.Add(2*time.Second), event.BlockTime)
+	assert.Equal(t, defaultSourceName, event.Source)
+
+	// not decoded
+	db.AddAction(&DatabaseMemoryAction{Offset: 15, BlockNum: 1, ActAccount: "casino", ActName: defaultContractActionName})
+	_, err = monitor.fetchEvent(context.Background(), db, 15)
+	assert.Error(t, err)
+
+	events, err := monitor.fetchAllEvents(context.Background(), db, 12, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(13), events[1].Offset)
+}
@@ -0,0 +1,395 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// Period of removing games not updated for the retention time
+	gameTrackerCleanupPeriod = time.Minute
+
+	// Period of checking for stuck games
+	gameTrackerWatchdogPeriod = 5 * time.Second
+)
+
+var errGameNotFound = errors.New("game not found")
+
+type GameState string
+
+const (
+	GameStateStarted        GameState = "started"
+	GameStateActionRequest  GameState = "action_request"
+	GameStateSignidicePart1 GameState = "signidice_part_1"
+	GameStateSignidicePart2 GameState = "signidice_part_2"
+	GameStateFinished       GameState = "finished"
+	GameStateFailed         GameState = "failed"
+)
+
+var gameStateByEventType = map[int]GameState{
+	EventGameStarted:           GameStateStarted,
+	EventActionRequest:         GameStateActionRequest,
+	EventSignidicePart1Request: GameStateSignidicePart1,
+	EventSignidicePart2Request: GameStateSignidicePart2,
+	EventGameFinished:          GameStateFinished,
+	EventGameFailed:            GameStateFailed,
+}
+
+func (s GameState) isFinal() bool {
+	return s == GameStateFinished || s == GameStateFailed
+}
+
+func (s GameState) isValid() bool {
+	for _, state := range gameStateByEventType {
+		if state == s {
+			return true
+		}
+	}
+	return false
+}
+
+type GameHistoryEvent struct {
+	*Event
+	Time time.Time `json:"time"`
+}
+
+type Game struct {
+	Source   string    `json:"source,omitempty"`
+	CasinoID uint64    `json:"casino_id"`
+	GameID   uint64    `json:"game_id"`
+	State    GameState `json:"state"`
+	// time of the first tracked event
+	CreatedAt time.Time `json:"created_at"`
+	// time the game entered the current state
+	StateAt   time.Time           `json:"state_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+	Events    []*GameHistoryEvent `json:"events"`
+
+	// state of the last stuck game alert
+	alerted GameState
+	// signidice block times by req_id
+	signidice map[uint64]*signidiceTimes
+}
+
+func (g *Game) copy() *Game {
+	result := *g
+	result.Events = append([]*GameHistoryEvent(nil), g.Events...)
+	result.signidice = nil
+	return &result
+}
+
+type gameKey struct {
+	source   string
+	casinoID uint64
+	gameID   uint64
+}
+
+type GameTrackerQueryMessage struct {
+	source   string
+	casinoID uint64
+	gameID   uint64
+	response chan *GameTrackerResponseMessage
+}
+
+type GameTrackerResponseMessage struct {
+	game *Game
+	err  error
+}
+
+// GameTracker keeps the state and the event history of every game seen by the scraper
+type GameTracker struct {
+	scraper       *Scraper
+	metrics       *metrics.Metrics
+	log           *zap.Logger
+	retention     time.Duration
+	maxEvents     int
+	stuckTimeouts map[GameState]time.Duration
+
+	track chan *Event
+	query chan *GameTrackerQueryMessage
+
+	games map[gameKey]*Game
+}
+
+func newGameTracker(monitor *Monitor) *GameTracker {
+	return &GameTracker{
+		scraper:       monitor.scraper,
+		metrics:       monitor.metrics,
+		log:           monitor.log.Scraper.Named("games"),
+		retention:     monitor.config.games.retention,
+		maxEvents:     monitor.config.games.maxEvents,
+		stuckTimeouts: monitor.config.games.stuckTimeouts,
+		track:         make(chan *Event),
+		query:         make(chan *GameTrackerQueryMessage),
+		games:         make(map[gameKey]*Game),
+	}
+}
+
+func (t *GameTracker) run(parentContext context.Context) {
+	defer func() {
+		t.log.Info("game tracker stopped")
+	}()
+	t.log.Info("game tracker started")
+
+	ticker := time.NewTicker(gameTrackerCleanupPeriod)
+	defer ticker.Stop()
+
+	watchdog := time.NewTicker(gameTrackerWatchdogPeriod)
+	defer watchdog.Stop()
+
+	for {
+		select {
+		case <-parentContext.Done():
+			t.log.Debug("parent context done")
+			return
+
+		case event := <-t.track:
+			t.update(event, time.Now())
+
+		case message := <-t.query:
+			response := new(GameTrackerResponseMessage)
+			if game, ok := t.games[gameKey{message.source, message.casinoID, message.gameID}]; ok {
+				response.game = game.copy()
+			} else {
+				response.err = errGameNotFound
+			}
+
+			message.response <- response
+			close(message.response)
+
+		case now := <-ticker.C:
+			t.cleanup(now)
+
+		case now := <-watchdog.C:
+			for _, alert := range t.findStuck(now) {
+				t.log.Info("stuck game",
+					zap.Uint64("casino_id", alert.CasinoID),
+					zap.Uint64("game_id", alert.GameID),
+					zap.ByteString("alert", alert.Data),
+				)
+
+				select {
+				case <-parentContext.Done():
+					return
+				case t.scraper.broadcast <- &ScraperBroadcastMessage{eventTopic(alert), alert, nil}:
+				}
+			}
+		}
+	}
+}
+
+func (t *GameTracker) update(event *Event, now time.Time) {
+	key := gameKey{event.Source, event.CasinoID, event.GameID}
+	game, ok := t.games[key]
+	if !ok {
+		game = &Game{
+			Source:    event.Source,
+			CasinoID:  event.CasinoID,
+			GameID:    event.GameID,
+			CreatedAt: now,
+			Events:    make([]*GameHistoryEvent, 0),
+		}
+		t.games[key] = game
+	}
+
+	game.Events = append(game.Events, &GameHistoryEvent{event, now})
+	if len(game.Events) > t.maxEvents {
+		game.Events = game.Events[len(game.Events)-t.maxEvents:]
+	}
+	game.UpdatedAt = now
+
+	state, ok := gameStateByEventType[event.EventType]
+	if ok && !game.State.isFinal() && state != game.State {
+		game.State = state
+		game.StateAt = now
+	}
+
+	game.observeSignidiceLatency(t.metrics, event)
+
+	t.log.Debug("game update",
+		zap.Uint64("casino_id", event.CasinoID),
+		zap.Uint64("game_id", event.GameID),
+		zap.String("state", string(game.State)),
+	)
+}
+
+// findStuck updates the stuck games gauge and returns alerts for the games stuck since the last check
+func (t *GameTracker) findStuck(now time.Time) []*Event {
+	alerts := make([]*Event, 0)
+	counts := make(map[GameState]int)
+
+	for _, game := range t.games {
+		timeout, ok := t.stuckTimeouts[game.State]
+		if !ok || now.Sub(game.StateAt) <= timeout {
+			continue
+		}
+
+		counts[game.State]++
+		if game.alerted == game.State {
+			continue
+		}
+
+		game.alerted = game.State
+		alert, err := newStuckGameAlert(game, now)
+		if err != nil {
+			t.log.Error("stuck game alert error", zap.Error(err))
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	for state := range t.stuckTimeouts {
+		t.metrics.GamesStuck.WithLabelValues(string(state)).Set(float64(counts[state]))
+	}
+
+	return alerts
+}
+
+func newStuckGameAlert(game *Game, now time.Time) (*Event, error) {
+	data, err := json.Marshal(&StuckGameAlertData{
+		State:        string(game.State),
+		StateAt:      game.StateAt,
+		StuckSeconds: now.Sub(game.StateAt).Seconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the alert has no offset, it is out of the offset stream of the chain events
+	last := game.Events[len(game.Events)-1]
+	return &Event{
+		Sender:    last.Sender,
+		CasinoID:  game.CasinoID,
+		GameID:    game.GameID,
+		RequestID: last.RequestID,
+		EventType: EventAlertStuckGame,
+		Data:      data,
+		BlockTime: last.BlockTime,
+		Source:    game.Source,
+	}, nil
+}
+
+// signidiceTimes are the block times of the signidice parts of one request
+type signidiceTimes struct {
+	part1 time.Time
+	part2 time.Time
+}
+
+// observeSignidiceLatency measures by block time part 1 → part 2 and part 2 → game finished
+// of the same req_id, the times are kept apart from the trimmed history until the game is finished
+func (g *Game) observeSignidiceLatency(observer *metrics.Metrics, event *Event) {
+	if event.BlockTime.IsZero() {
+		return
+	}
+
+	casinoID := strconv.FormatUint(g.CasinoID, 10)
+	switch event.EventType {
+	case EventSignidicePart1Request:
+		g.signidiceRequest(event.RequestID).part1 = event.BlockTime
+	case EventSignidicePart2Request:
+		times := g.signidiceRequest(event.RequestID)
+		times.part2 = event.BlockTime
+		if !times.part1.IsZero() {
+			observer.SignidicePart1ToPart2Seconds.WithLabelValues(casinoID).
+				Observe(event.BlockTime.Sub(times.part1).Seconds())
+		}
+	case EventGameFinished:
+		times, ok := g.signidice[event.RequestID]
+		if ok && !times.part2.IsZero() {
+			observer.SignidicePart2ToFinishSeconds.WithLabelValues(casinoID).
+				Observe(event.BlockTime.Sub(times.part2).Seconds())
+		}
+		g.signidice = nil
+	}
+}
+
+func (g *Game) signidiceRequest(requestID uint64) *signidiceTimes {
+	if g.signidice == nil {
+		g.signidice = make(map[uint64]*signidiceTimes)
+	}
+
+	times, ok := g.signidice[requestID]
+	if !ok {
+		times = new(signidiceTimes)
+		g.signidice[requestID] = times
+	}
+	return times
+}
+
+func (t *GameTracker) cleanup(now time.Time) {
+	for key, game := range t.games {
+		if now.Sub(game.UpdatedAt) > t.retention {
+			delete(t.games, key)
+		}
+	}
+}
+
+// call from scraper, blocks until the tracker receives the event
+func (t *GameTracker) add(parentContext context.Context, event *Event) {
+	select {
+	case <-parentContext.Done():
+	case t.track <- event:
+	}
+}
+
+func (t *GameTracker) get(parentContext context.Context, source string, casinoID uint64, gameID uint64) (*Game, error) {
+	message := &GameTrackerQueryMessage{
+		source:   source,
+		casinoID: casinoID,
+		gameID:   gameID,
+		response: make(chan *GameTrackerResponseMessage, 1),
+	}
+
+	select {
+	case <-parentContext.Done():
+		return nil, parentContext.Err()
+	case t.query <- message:
+	}
+
+	response := <-message.response
+	return response.game, response.err
+}
+
+// GET /games/{casino_id}/{game_id}?token=&source=
+func (m *Monitor) serveGame(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	casinoID, err := strconv.ParseUint(vars["casino_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid casino_id", http.StatusBadRequest)
+		return
+	}
+
+	gameID, err := strconv.ParseUint(vars["game_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid game_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.checkToken(r.Context(), r.URL.Query().Get("token")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	game, err := m.games.get(r.Context(), r.URL.Query().Get("source"), casinoID, gameID)
+	switch err {
+	case nil:
+	case errGameNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(game); err != nil {
+		m.log.Main.Error("game response error", zap.Error(err))
+	}
+}
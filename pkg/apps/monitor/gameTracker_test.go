@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGameTrackerUpdate(t *testing.T) {
+	tracker := newGameTracker(newTestMonitor(t))
+	now := time.Now()
+
+	cases := []struct {
+		eventType int
+		expected  GameState
+	}{
+		{EventGameStarted, GameStateStarted},
+		{EventActionRequest, GameStateActionRequest},
+		{EventSignidicePart1Request, GameStateSignidicePart1},
+		{EventSignidicePart2Request, GameStateSignidicePart2},
+		{EventGameMessage, GameStateSignidicePart2},
+		{EventGameFinished, GameStateFinished},
+		{EventGameFailed, GameStateFinished},
+	}
+
+	for i, tc := range cases {
+		tracker.update(&Event{Offset: uint64(i), CasinoID: 1, GameID: 2, EventType: tc.eventType}, now.Add(time.Duration(i)*time.Second))
+		assert.Equal(t, tc.expected, tracker.games[gameKey{"", 1, 2}].State)
+	}
+
+	game := tracker.games[gameKey{"", 1, 2}]
+	assert.Equal(t, len(cases), len(game.Events))
+	assert.Equal(t, now, game.CreatedAt)
+	assert.Equal(t, now.Add(5*time.Second), game.StateAt)
+	assert.Equal(t, now.Add(6*time.Second), game.UpdatedAt)
+}
+
+func TestGameTrackerCleanup(t *testing.T) {
+	tracker := newGameTracker(newTestMonitor(t))
+	now := time.Now()
+
+	tracker.update(&Event{CasinoID: 1, GameID: 1}, now.Add(-2*tracker.retention))
+	tracker.update(&Event{CasinoID: 1, GameID: 2}, now)
+	tracker.cleanup(now)
+
+	_, ok := tracker.games[gameKey{"", 1, 1}]
+	assert.False(t, ok)
+	_, ok = tracker.games[gameKey{"", 1, 2}]
+	assert.True(t, ok)
+}
+
+func TestGameTrackerGet(t *testing.T) {
+	monitor := newTestMonitor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+
+	monitor.games.add(ctx, &Event{Offset: 10, CasinoID: 1, GameID: 2, EventType: EventGameStarted})
+
+	game, err := monitor.games.get(ctx, "", 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, GameStateStarted, game.State)
+	assert.Equal(t, uint64(10), game.Events[0].Offset)
+
+	_, err = monitor.games.get(ctx, "", 1, 3)
+	assert.Equal(t, errGameNotFound, err)
+}
+
+func TestServeGame(t *testing.T) {
+	monitor := newTestMonitor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+
+	monitor.games.add(ctx, &Event{Offset: 10, CasinoID: 1, GameID: 2, EventType: EventActionRequest})
+
+	cases := []struct {
+		url      string
+		expected int
+	}{
+		{"/games/1/2?token=test", http.StatusOK},
+		{"/games/1/3?token=test", http.StatusNotFound},
+		{"/games/test/3?token=test", http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			monitor.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", tc.url, nil))
+			assert.Equal(t, tc.expected, recorder.Code)
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	monitor.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/games/1/2?token=test", nil))
+
+	game := new(Game)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), game))
+	assert.Equal(t, GameStateActionRequest, game.State)
+	assert.Equal(t, 1, len(game.Events))
+}
+
+func TestGameTrackerFindStuck(t *testing.T) {
+	tracker := newGameTracker(newTestMonitor(t))
+	tracker.stuckTimeouts = map[GameState]time.Duration{GameStateSignidicePart1: time.Minute}
+	now := time.Now()
+
+	tracker.update(&Event{Offset: 1, CasinoID: 1, GameID: 1, EventType: EventSignidicePart1Request}, now.Add(-2*time.Minute))
+	tracker.update(&Event{Offset: 2, CasinoID: 1, GameID: 2, EventType: EventSignidicePart1Request}, now)
+	tracker.update(&Event{Offset: 3, CasinoID: 1, GameID: 3, EventType: EventGameStarted}, now.Add(-2*time.Minute))
+
+	alerts := tracker.findStuck(now)
+	require.Equal(t, 1, len(alerts))
+	assert.Equal(t, uint64(1), alerts[0].GameID)
+	assert.Equal(t, uint64(0), alerts[0].Offset)
+	assert.True(t, alerts[0].IsSynthetic())
+
+	payload, err := alerts[0].Payload()
+	require.NoError(t, err)
+	assert.Equal(t, string(GameStateSignidicePart1), payload.(*StuckGameAlertData).State)
+	assert.Equal(t, float64(120), payload.(*StuckGameAlertData).StuckSeconds)
+	assert.Equal(t, float64(1), testutil.ToFloat64(tracker.metrics.GamesStuck.WithLabelValues(string(GameStateSignidicePart1))))
+
+	// one alert per state
+	assert.Equal(t, 0, len(tracker.findStuck(now.Add(time.Minute))))
+
+	tracker.update(&Event{Offset: 4, CasinoID: 1, GameID: 1, EventType: EventGameFinished}, now)
+	assert.Equal(t, 0, len(tracker.findStuck(now.Add(time.Minute))))
+	assert.Equal(t, float64(0), testutil.ToFloat64(tracker.metrics.GamesStuck.WithLabelValues(string(GameStateSignidicePart1))))
+}
+
+func TestGameTrackerMaxEvents(t *testing.T) {
+	tracker := newGameTracker(newTestMonitor(t))
+	tracker.maxEvents = 3
+	now := time.Now()
+
+	for offset := uint64(1); offset <= 5; offset++ {
+		tracker.update(&Event{Offset: offset, CasinoID: 1, GameID: 1, EventType: EventGameMessage}, now)
+	}
+
+	game := tracker.games[gameKey{"", 1, 1}]
+	require.Equal(t, 3, len(game.Events))
+	assert.Equal(t, uint64(3), game.Events[0].Offset)
+	assert.Equal(t, uint64(5), game.Events[2].Offset)
+}
+
+func TestSendEventsFromDatabaseSyntheticTopic(t *testing.T) {
+	session := newSession(newTestMonitor(t), nil)
+	require.NoError(t, session.sendEventsFromDatabase(context.Background(), topicAlertStuckGame, 0))
+	require.NoError(t, session.sendBatchEventsFromDatabase(context.Background(), []string{topicAlertStuckGame}, 0))
+}
+
+func TestGameTrackerSignidiceLatency(t *testing.T) {
+	tracker := newGameTracker(newTestMonitor(t))
+	now := time.Now()
+	blockTime := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []*Event{
+		{CasinoID: 31, GameID: 1, RequestID: 1, EventType: EventSignidicePart1Request, BlockTime: blockTime},
+		{CasinoID: 31, GameID: 1, RequestID: 2, EventType: EventSignidicePart1Request, BlockTime: blockTime.Add(time.Second)},
+		{CasinoID: 31, GameID: 1, RequestID: 2, EventType: EventSignidicePart2Request, BlockTime: blockTime.Add(2 * time.Second)},
+		{CasinoID: 31, GameID: 1, RequestID: 2, EventType: EventGameFinished, BlockTime: blockTime.Add(5 * time.Second)},
+	}
+
+	part1ToPart2 := readHistogram(t, tracker.metrics.SignidicePart1ToPart2Seconds, "31")
+	part2ToFinish := readHistogram(t, tracker.metrics.SignidicePart2ToFinishSeconds, "31")
+	for _, event := range events {
+		tracker.update(event, now)
+	}
+
+	assertHistogramDelta(t, tracker.metrics.SignidicePart1ToPart2Seconds, "31", part1ToPart2, 1, 1)
+	assertHistogramDelta(t, tracker.metrics.SignidicePart2ToFinishSeconds, "31", part2ToFinish, 1, 3)
+}
+
+func TestGameTrackerSignidiceLatencyTrimmedHistory(t *testing.T) {
+	tracker := newGameTracker(newTestMonitor(t))
+	tracker.maxEvents = 2
+	now := time.Now()
+	blockTime := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []*Event{
+		{CasinoID: 32, GameID: 1, RequestID: 3, EventType: EventSignidicePart1Request, BlockTime: blockTime},
+		{CasinoID: 32, GameID: 1, RequestID: 3, EventType: EventSignidicePart2Request, BlockTime: blockTime.Add(time.Second)},
+		{CasinoID: 32, GameID: 1, RequestID: 4, EventType: EventSignidicePart2Request, BlockTime: blockTime.Add(2 * time.Second)},
+		{CasinoID: 32, GameID: 1, RequestID: 4, EventType: EventActionRequest, BlockTime: blockTime.Add(3 * time.Second)},
+		{CasinoID: 32, GameID: 1, RequestID: 4, EventType: EventActionRequest, BlockTime: blockTime.Add(4 * time.Second)},
+		{CasinoID: 32, GameID: 1, RequestID: 3, EventType: EventGameFinished, BlockTime: blockTime.Add(10 * time.Second)},
+	}
+
+	part1ToPart2 := readHistogram(t, tracker.metrics.SignidicePart1ToPart2Seconds, "32")
+	part2ToFinish := readHistogram(t, tracker.metrics.SignidicePart2ToFinishSeconds, "32")
+	for _, event := range events {
+		tracker.update(event, now)
+	}
+
+	// the part 2 of req_id 3 is out of the history, the finish is matched to it and not to req_id 4
+	assertHistogramDelta(t, tracker.metrics.SignidicePart1ToPart2Seconds, "32", part1ToPart2, 1, 1)
+	assertHistogramDelta(t, tracker.metrics.SignidicePart2ToFinishSeconds, "32", part2ToFinish, 1, 9)
+}
+
+func readHistogram(t *testing.T, histogram *prometheus.HistogramVec, casinoID string) *dto.Histogram {
+	metric := new(dto.Metric)
+	require.NoError(t, histogram.WithLabelValues(casinoID).(prometheus.Histogram).Write(metric))
+	return metric.GetHistogram()
+}
+
+// assertHistogramDelta compares the observations since before
+func assertHistogramDelta(t *testing.T, histogram *prometheus.HistogramVec, casinoID string, before *dto.Histogram, count uint64, sum float64) {
+	after := readHistogram(t, histogram, casinoID)
+	assert.Equal(t, count, after.GetSampleCount()-before.GetSampleCount())
+	assert.Equal(t, sum, after.GetSampleSum()-before.GetSampleSum())
+}
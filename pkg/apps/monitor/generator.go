@@ -0,0 +1,340 @@
+package monitor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	generatorDistributionUniform = "uniform"
+	generatorDistributionZipf    = "zipf"
+
+	// the account of the generated actions if the source matches any account
+	generatorAccount = "casino"
+
+	// chance of an event out of the game lifecycle, eg game_message, between the lifecycle events
+	generatorExtraEventChance = 0.2
+	// chance of game_failed instead of game_finished
+	generatorFailChance = 0.1
+
+	// depth of the nested structs and arrays of the generated values
+	generatorMaxDepth = 8
+
+	generatorNameChars = "abcdefghijklmnopqrstuvwxyz12345"
+
+	// Period of removing the expired generated actions from the memory database
+	generatorCleanupPeriod = time.Minute
+)
+
+// the event types of a game in order, the configured ones are generated
+var (
+	generatorLifecycle = []int{EventGameStarted, EventActionRequest, EventSignidicePart1Request, EventSignidicePart2Request}
+	generatorFinals    = []int{EventGameFinished, EventGameFailed}
+)
+
+// Generator emits the actions of synthetic games for all configured event types,
+// the act_data is encoded with the ABIs of the sources, so the events go through the full decoding
+type Generator struct {
+	config  *GeneratorConfig
+	random  *rand.Rand
+	sources []*generatorSource
+	games   []*generatorGame
+	// index of the casino of a new game and of the game of the next event
+	casino func() int
+	game   func() int
+
+	offset     uint64
+	nextGameID uint64
+}
+
+type generatorSource struct {
+	decoder    *AbiDecoder
+	actAccount string
+	actName    string
+	lifecycle  []int
+	finals     []int
+	// configured event types out of the lifecycle
+	extras []int
+}
+
+type generatorGame struct {
+	source    *generatorSource
+	casinoID  uint64
+	gameID    uint64
+	requestID uint64
+	// index of the next lifecycle event
+	step int
+}
+
+func configuredTypes(types []int, configured map[int]*Decoder) []int {
+	result := make([]int, 0, len(types))
+	for _, eventType := range types {
+		if _, ok := configured[eventType]; ok {
+			result = append(result, eventType)
+		}
+	}
+	return result
+}
+
+// NewGenerator checks that the values of every event type of the sources can be generated,
+// the offsets start from the current time in microseconds to keep them growing after restart
+func NewGenerator(config *Config, decoder *AbiDecoder) (*Generator, error) {
+	seed := config.generator.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	g := &Generator{
+		config:     &config.generator,
+		random:     rand.New(rand.NewSource(seed)),
+		offset:     uint64(time.Now().UnixNano() / int64(time.Microsecond)),
+		nextGameID: 1,
+	}
+	g.casino = g.distribution(config.generator.casinos)
+	g.game = g.distribution(config.generator.games)
+
+	for _, source := range config.getSources() {
+		sourceDecoder, err := decoder.source(source.name)
+		if err != nil {
+			return nil, err
+		}
+
+		s := &generatorSource{decoder: sourceDecoder, actAccount: generatorAccount, actName: sourceDecoder.actionName}
+		if source.filter.actAccount != nil {
+			s.actAccount = *source.filter.actAccount
+		}
+		if source.filter.actName != nil {
+			s.actName = *source.filter.actName
+		}
+
+		s.lifecycle = configuredTypes(generatorLifecycle, sourceDecoder.events)
+		s.finals = configuredTypes(generatorFinals, sourceDecoder.events)
+		for eventType := range sourceDecoder.events {
+			if !containsInt(generatorLifecycle, eventType) && !containsInt(generatorFinals, eventType) {
+				s.extras = append(s.extras, eventType)
+			}
+		}
+		sort.Ints(s.extras)
+
+		for eventType := range sourceDecoder.events {
+			if _, err := s.encode(g.random, &generatorGame{source: s}, eventType); err != nil {
+				return nil, fmt.Errorf("source %q event %d: %s", source.name, eventType, err)
+			}
+		}
+		g.sources = append(g.sources, s)
+	}
+
+	return g, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// distribution returns the picker of an index from 0 to n-1
+func (g *Generator) distribution(n int) func() int {
+	if g.config.distribution == generatorDistributionZipf && n > 1 {
+		zipf := rand.NewZipf(g.random, 1.1, 1, uint64(n-1))
+		return func() int {
+			return int(zipf.Uint64())
+		}
+	}
+	return func() int {
+		return g.random.Intn(n)
+	}
+}
+
+func (g *Generator) newGame() *generatorGame {
+	game := &generatorGame{
+		source:   g.sources[g.random.Intn(len(g.sources))],
+		casinoID: uint64(g.casino() + 1),
+		gameID:   g.nextGameID,
+	}
+	g.nextGameID++
+	return game
+}
+
+// nextEventType returns the event type of the next event of the game and true if the game is over
+func (g *Generator) nextEventType(game *generatorGame) (int, bool) {
+	s := game.source
+	if len(s.extras) != 0 && (game.step > 0 || len(s.lifecycle) == 0) && g.random.Float64() < generatorExtraEventChance {
+		return s.extras[g.random.Intn(len(s.extras))], false
+	}
+
+	if game.step < len(s.lifecycle) {
+		eventType := s.lifecycle[game.step]
+		game.step++
+		return eventType, game.step == len(s.lifecycle) && len(s.finals) == 0
+	}
+
+	switch {
+	case len(s.finals) == 0:
+		// only the extra event types are configured
+		return s.extras[g.random.Intn(len(s.extras))], false
+	case len(s.finals) > 1 && g.random.Float64() < generatorFailChance:
+		return s.finals[1], true
+	default:
+		return s.finals[0], true
+	}
+}
+
+// Next returns the action of the next event of a random game, the finished games are replaced by new ones
+func (g *Generator) Next(now time.Time) (*ActionTraceRows, error) {
+	for len(g.games) < g.config.games {
+		g.games = append(g.games, g.newGame())
+	}
+
+	index := g.game()
+	game := g.games[index]
+	eventType, over := g.nextEventType(game)
+	if over {
+		g.games[index] = g.newGame()
+	}
+
+	game.requestID++
+	actData, err := game.source.encode(g.random, game, eventType)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &ActionTraceRows{actData, g.offset, now, game.source.actAccount, game.source.actName}
+	g.offset++
+	return rows, nil
+}
+
+// encode returns act_data of the contract action with the random event data
+func (s *generatorSource) encode(random *rand.Rand, game *generatorGame, eventType int) ([]byte, error) {
+	events := s.decoder.events[eventType]
+	eventData, err := randomStruct(random, events.abi, defaultEventStructName, 0)
+	if err != nil {
+		return nil, err
+	}
+	eventJson, err := json.Marshal(eventData)
+	if err != nil {
+		return nil, err
+	}
+	data, err := events.abi.EncodeStruct(defaultEventStructName, eventJson)
+	if err != nil {
+		return nil, err
+	}
+
+	main := s.decoder.main.abi
+	action := main.ActionForName(eos.ActionName(s.decoder.actionName))
+	if action == nil {
+		return nil, fmt.Errorf("action %s not found", s.decoder.actionName)
+	}
+
+	// the other fields of the action are random
+	actionData, err := randomStruct(random, main, action.Type, 0)
+	if err != nil {
+		return nil, err
+	}
+	actionData["sender"] = "player"
+	actionData["casino_id"] = game.casinoID
+	actionData["game_id"] = game.gameID
+	actionData["req_id"] = game.requestID
+	actionData["event_type"] = eventType
+	actionData["data"] = hex.EncodeToString(data)
+
+	actionJson, err := json.Marshal(actionData)
+	if err != nil {
+		return nil, err
+	}
+	return main.EncodeAction(eos.ActionName(s.decoder.actionName), actionJson)
+}
+
+func randomStruct(random *rand.Rand, abi *eos.ABI, name string, depth int) (map[string]interface{}, error) {
+	fields, err := structFields(abi, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, err := randomValue(random, abi, field.Type, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("struct %s field %s: %s", name, field.Name, err)
+		}
+		result[field.Name] = value
+	}
+	return result, nil
+}
+
+func randomHex(random *rand.Rand, size int) string {
+	data := make([]byte, size)
+	random.Read(data)
+	return hex.EncodeToString(data)
+}
+
+// randomValue returns the JSON value of the type for the eos-go encoder, the optional and binary extension fields are set
+func randomValue(random *rand.Rand, abi *eos.ABI, fieldType string, depth int) (interface{}, error) {
+	if depth > generatorMaxDepth {
+		return nil, fmt.Errorf("type %s is nested too deep", fieldType)
+	}
+
+	fieldType = resolveType(abi, strings.TrimRight(fieldType, "?$"))
+	if strings.HasSuffix(fieldType, "[]") {
+		values := make([]interface{}, random.Intn(3))
+		for i := range values {
+			value, err := randomValue(random, abi, strings.TrimSuffix(fieldType, "[]"), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	switch fieldType {
+	case "bool":
+		return random.Intn(2) == 1, nil
+	case "int8", "int16", "int32", "int64", "varint32":
+		return random.Intn(200) - 100, nil
+	case "uint8", "uint16", "uint32", "uint64", "varuint32":
+		return random.Intn(100), nil
+	case "float32", "float64":
+		return random.Float64() * 100, nil
+	case "name":
+		name := make([]byte, 12)
+		for i := range name {
+			name[i] = generatorNameChars[random.Intn(len(generatorNameChars))]
+		}
+		return string(name), nil
+	case "string":
+		return fmt.Sprintf("synthetic %d", random.Intn(1000)), nil
+	case "bytes":
+		return randomHex(random, random.Intn(16)), nil
+	case "checksum160":
+		return randomHex(random, 20), nil
+	case "checksum256":
+		return randomHex(random, 32), nil
+	case "checksum512":
+		return randomHex(random, 64), nil
+	case "asset":
+		return fmt.Sprintf("%d.%04d BET", random.Intn(1000), random.Intn(10000)), nil
+	case "symbol":
+		return "4,BET", nil
+	case "time_point_sec":
+		return time.Now().UTC().Format("2006-01-02T15:04:05"), nil
+	case "time_point":
+		return time.Now().UTC().Format("2006-01-02T15:04:05.000"), nil
+	case "block_timestamp_type":
+		return time.Now().UTC().Format("2006-01-02T15:04:05.000000-07:00"), nil
+	}
+
+	if abi.StructForName(fieldType) != nil {
+		return randomStruct(random, abi, fieldType, depth)
+	}
+	return nil, fmt.Errorf("type %s can not be generated", fieldType)
+}
@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEventAbis = map[int]string{
+	EventGameStarted:           "../../../configs/abi/events/game_started.abi",
+	EventActionRequest:         "../../../configs/abi/events/action_request.abi",
+	EventSignidicePart1Request: "../../../configs/abi/events/signidice_part_1_request.abi",
+	EventSignidicePart2Request: "../../../configs/abi/events/signidice_part_2_request.abi",
+	EventGameFinished:          "../../../configs/abi/events/game_finished.abi",
+	EventGameFailed:            "../../../configs/abi/events/game_failed.abi",
+	EventGameMessage:           "../../../configs/abi/events/game_message.abi",
+}
+
+func newTestGenerator(t *testing.T, events map[int]string) (*Generator, *Config, *AbiDecoder) {
+	config := newConfig()
+	config.abi.events = events
+	config.generator.seed = 1
+	require.NoError(t, config.validate())
+
+	decoder, err := NewAbiDecoder(config, testLoggers.Decoder)
+	require.NoError(t, err)
+
+	generator, err := NewGenerator(config, decoder)
+	require.NoError(t, err)
+	return generator, config, decoder
+}
+
+func TestGeneratorEvents(t *testing.T) {
+	generator, config, decoder := newTestGenerator(t, testEventAbis)
+
+	eventTypes := make(map[int]int)
+	games := make(map[uint64][]int)
+	var offset uint64
+	for i := 0; i < 500; i++ {
+		rows, err := generator.Next(time.Now())
+		require.NoError(t, err)
+		if i > 0 {
+			assert.Equal(t, offset+1, rows.offset)
+		}
+		offset = rows.offset
+
+		event, err := decoder.decodeRows(config.getSources(), rows)
+		require.NoError(t, err)
+		assert.True(t, event.CasinoID >= 1 && event.CasinoID <= uint64(config.generator.casinos))
+		assert.Equal(t, "player", event.Sender)
+
+		_, err = event.Payload()
+		require.NoError(t, err)
+
+		eventTypes[event.EventType]++
+		games[event.GameID] = append(games[event.GameID], event.EventType)
+	}
+
+	for eventType := range testEventAbis {
+		assert.NotZero(t, eventTypes[eventType], EventTypeName(eventType))
+	}
+
+	// the games follow the lifecycle with the messages between the events
+	for gameID, events := range games {
+		assert.Equal(t, EventGameStarted, events[0], gameID)
+		lifecycle := make([]int, 0)
+		for _, eventType := range events {
+			if eventType != EventGameMessage {
+				lifecycle = append(lifecycle, eventType)
+			}
+		}
+		for i, eventType := range lifecycle {
+			if i < len(generatorLifecycle) {
+				assert.Equal(t, generatorLifecycle[i], eventType, gameID)
+			} else {
+				assert.Contains(t, generatorFinals, eventType, gameID)
+			}
+		}
+	}
+}
+
+func TestGeneratorDefaultAbi(t *testing.T) {
+	generator, config, decoder := newTestGenerator(t, map[int]string{0: defaultEventABI})
+
+	for i := 0; i < 10; i++ {
+		rows, err := generator.Next(time.Now())
+		require.NoError(t, err)
+		event, err := decoder.decodeRows(config.getSources(), rows)
+		require.NoError(t, err)
+		assert.Equal(t, 0, event.EventType)
+		assert.Contains(t, string(event.Data), `"c":"synthetic`)
+	}
+}
+
+func TestGeneratorZipf(t *testing.T) {
+	config := newConfig()
+	config.generator.seed = 1
+	config.generator.casinos = 5
+	config.generator.games = 1
+	config.generator.distribution = generatorDistributionZipf
+
+	decoder, err := NewAbiDecoder(config, testLoggers.Decoder)
+	require.NoError(t, err)
+	generator, err := NewGenerator(config, decoder)
+	require.NoError(t, err)
+
+	casinos := make(map[uint64]int)
+	for i := 0; i < 200; i++ {
+		rows, err := generator.Next(time.Now())
+		require.NoError(t, err)
+		event, err := decoder.decodeRows(config.getSources(), rows)
+		require.NoError(t, err)
+		casinos[event.CasinoID]++
+	}
+
+	for casinoID := uint64(2); casinoID <= 5; casinoID++ {
+		assert.True(t, casinos[1] > casinos[casinoID], "casino %d", casinoID)
+	}
+}
+
+func TestRandomValue(t *testing.T) {
+	abi, err := eos.NewABI(strings.NewReader(`{
+		"version": "eosio::abi/1.1",
+		"types": [{"new_type_name": "amount", "type": "asset"}],
+		"structs": [
+			{"name": "item", "base": "", "fields": [{"name": "id", "type": "uint64"}, {"name": "owner", "type": "name"}]},
+			{"name": "event_data", "base": "", "fields": [
+				{"name": "items", "type": "item[]"},
+				{"name": "amount", "type": "amount"},
+				{"name": "note", "type": "string?"},
+				{"name": "hash", "type": "checksum256$"}
+			]},
+			{"name": "unsupported", "base": "", "fields": [{"name": "key", "type": "public_key"}]}
+		]
+	}`))
+	require.NoError(t, err)
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		value, err := randomStruct(random, abi, defaultEventStructName, 0)
+		require.NoError(t, err)
+		assert.Len(t, value, 4)
+
+		data, err := abi.EncodeStruct(defaultEventStructName, mustMarshal(t, value))
+		require.NoError(t, err)
+		_, err = abi.Decode(eos.NewDecoder(data), defaultEventStructName)
+		require.NoError(t, err)
+	}
+
+	_, err = randomStruct(random, abi, "unsupported", 0)
+	assert.Error(t, err)
+}
+
+func TestScraperGenerate(t *testing.T) {
+	monitor := newTestMonitor(t)
+	monitor.config.generator.rate = 1000
+
+	session := newSession(monitor, nil)
+	subscribe := &ScraperSubscribeMessage{name: eventTopicName(0), session: session, response: make(chan *ScraperResponseMessage)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+	go monitor.scraper.run(ctx)
+	monitor.scraper.subscribe <- subscribe
+	<-subscribe.response
+
+	var offset uint64
+	for i := 0; i < 3; i++ {
+		select {
+		case event := <-session.queue:
+			assert.True(t, event.Offset > offset)
+			offset = event.Offset
+		case <-time.After(time.Second):
+			t.Fatal("no generated event")
+		}
+	}
+	assert.True(t, monitor.scraper.listening.IsSet())
+}
+
+func TestScraperGenerateMemory(t *testing.T) {
+	config := newConfig()
+	config.generator.rate = 1000
+	abiDecoder, err := NewAbiDecoder(config, testLoggers.Decoder)
+	require.NoError(t, err)
+	db := NewDatabaseMemory()
+	monitor := NewMonitor(config, abiDecoder, db, db, testLoggers, nil)
+
+	session := newSession(monitor, nil)
+	subscribe := &ScraperSubscribeMessage{name: eventTopicName(0), session: session, response: make(chan *ScraperResponseMessage)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+	go monitor.scraper.run(ctx)
+	monitor.scraper.subscribe <- subscribe
+	<-subscribe.response
+
+	var event *Event
+	select {
+	case event = <-session.queue:
+	case <-time.After(time.Second):
+		t.Fatal("no generated event")
+	}
+
+	// the generated actions are the history of the subscriptions from an offset
+	events, err := monitor.fetchAllEvents(ctx, db, event.Offset-1, 0)
+	require.NoError(t, err)
+	require.True(t, len(events) > 0)
+	assert.Equal(t, event.Offset, events[0].Offset)
+	assert.Equal(t, event.GameID, events[0].GameID)
+}
+
+func mustMarshal(t *testing.T, value interface{}) []byte {
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+
+	healthCheckDatabase       = "database"
+	healthCheckSharedDatabase = "shared_database"
+	healthCheckListen         = "listen"
+	healthCheckNotifyLag      = "notify_lag"
+	healthCheckAbi            = "abi"
+)
+
+var (
+	errListenNotRunning = errors.New("listen loop is not running")
+	errReplayFinished   = errors.New("replay is finished")
+	errAbiNotLoaded     = errors.New("abi is not loaded")
+)
+
+type HealthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                  `json:"status"`
+	Checks map[string]*HealthCheck `json:"checks"`
+}
+
+type healthChecker func(ctx context.Context) error
+
+const (
+	sqlCheckDatabase  = "SELECT 1"
+	sqlFetchChainHead = "SELECT timestamp FROM chain.block_info ORDER BY block_num DESC LIMIT 1"
+)
+
+func checkDatabase(db DatabaseConnect) healthChecker {
+	return func(ctx context.Context) error {
+		one := 0
+		return db.QueryRow(ctx, sqlCheckDatabase).Scan(&one)
+	}
+}
+
+func (m *Monitor) checkListen(_ context.Context) error {
+	if m.scraper.replayed.IsSet() {
+		return errReplayFinished
+	}
+	if !m.scraper.listening.IsSet() {
+		return errListenNotRunning
+	}
+	return nil
+}
+
+// checkListenAlive passes after the end of the replay, the monitor is not restarted to replay it again
+func (m *Monitor) checkListenAlive(ctx context.Context) error {
+	if m.scraper.replayed.IsSet() {
+		return nil
+	}
+	return m.checkListen(ctx)
+}
+
+// checkNotifyLag fails if the chain head is ahead of the last notification for more than notifyLag,
+// ie blocks are produced but the monitor does not receive notifications.
+// With the notify filter only the matching actions are notified, the last of them is the head.
+func (m *Monitor) checkNotifyLag(ctx context.Context) error {
+	var head time.Time
+	var err error
+	if m.config.db.notifyFilter {
+		head, err = fetchLastActionTime(ctx, m.pool, m.metrics, sourceFilters(m.config.getSources()))
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+	} else {
+		err = m.pool.QueryRow(ctx, sqlFetchChainHead).Scan(&head)
+	}
+	if err != nil {
+		return fmt.Errorf("chain head query error: %s", err)
+	}
+
+	lastNotify := m.scraper.lastNotifyTime()
+	if lastNotify.IsZero() {
+		return errListenNotRunning
+	}
+
+	if lag := head.Sub(lastNotify); lag > m.config.health.notifyLag {
+		return fmt.Errorf("last notification is %s behind the chain head", lag)
+	}
+	return nil
+}
+
+func (m *Monitor) checkAbi(_ context.Context) error {
+	if m.abiDecoder == nil || m.abiDecoder.main == nil || len(m.abiDecoder.events) == 0 {
+		return errAbiNotLoaded
+	}
+	return nil
+}
+
+// checkHealth runs the checks in parallel, every check is limited by the health timeout
+func (m *Monitor) checkHealth(parentContext context.Context, checkers map[string]healthChecker) *HealthResponse {
+	type result struct {
+		name string
+		err  error
+	}
+
+	ctx, cancel := context.WithTimeout(parentContext, m.config.health.timeout)
+	defer cancel()
+
+	results := make(chan result, len(checkers))
+	for name, checker := range checkers {
+		go func(name string, checker healthChecker) {
+			results <- result{name, checker(ctx)}
+		}(name, checker)
+	}
+
+	response := &HealthResponse{Status: healthStatusOK, Checks: make(map[string]*HealthCheck)}
+	for range checkers {
+		r := <-results
+		check := &HealthCheck{Status: healthStatusOK}
+		if r.err != nil {
+			check.Status = healthStatusFail
+			check.Error = r.err.Error()
+			response.Status = healthStatusFail
+		}
+		response.Checks[r.name] = check
+	}
+
+	return response
+}
+
+func (m *Monitor) serveHealth(checkers map[string]healthChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := m.checkHealth(r.Context(), checkers)
+		if response.Status != healthStatusOK {
+			m.log.Main.Warn("health check failed", zap.String("path", r.URL.Path), zap.Any("checks", response.Checks))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if response.Status != healthStatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			m.log.Main.Error("health response error", zap.Error(err))
+		}
+	}
+}
+
+// GET /healthz, the monitor can not recover without restart
+func (m *Monitor) livenessCheckers() map[string]healthChecker {
+	return map[string]healthChecker{
+		healthCheckListen: m.checkListenAlive,
+		healthCheckAbi:    m.checkAbi,
+	}
+}
+
+// GET /readyz, the monitor can serve clients
+func (m *Monitor) readinessCheckers() map[string]healthChecker {
+	return map[string]healthChecker{
+		healthCheckDatabase:       checkDatabase(m.pool),
+		healthCheckSharedDatabase: checkDatabase(m.sharedPool),
+		healthCheckListen:         m.checkListen,
+		healthCheckNotifyLag:      m.checkNotifyLag,
+		healthCheckAbi:            m.checkAbi,
+	}
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// healthDatabase answers the health check queries
+type healthDatabase struct {
+	DatabaseMock
+	head time.Time
+	err  error
+}
+
+type healthDatabaseRow struct {
+	db *healthDatabase
+}
+
+func (r *healthDatabaseRow) Scan(dest ...interface{}) error {
+	if r.db.err != nil {
+		return r.db.err
+	}
+
+	switch value := dest[0].(type) {
+	case *int:
+		*value = 1
+	case *time.Time:
+		*value = r.db.head
+	}
+	return nil
+}
+
+func (db *healthDatabase) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &healthDatabaseRow{db}
+}
+
+func serveHealthTest(t *testing.T, monitor *Monitor, path string) (int, *HealthResponse) {
+	recorder := httptest.NewRecorder()
+	monitor.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+	response := new(HealthResponse)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), response))
+	return recorder.Code, response
+}
+
+func TestHealthLiveness(t *testing.T) {
+	monitor := newTestMonitor(t)
+
+	code, response := serveHealthTest(t, monitor, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthStatusFail, response.Status)
+	assert.Equal(t, healthStatusOK, response.Checks[healthCheckAbi].Status)
+	assert.Equal(t, errListenNotRunning.Error(), response.Checks[healthCheckListen].Error)
+
+	monitor.scraper.listening.Set()
+
+	code, response = serveHealthTest(t, monitor, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthStatusOK, response.Status)
+	assert.Len(t, response.Checks, 2)
+}
+
+func TestHealthReadiness(t *testing.T) {
+	now := time.Now()
+	monitor := newTestMonitor(t)
+	db := &healthDatabase{head: now}
+	shared := &healthDatabase{err: errors.New("connection refused")}
+	monitor.pool, monitor.sharedPool = db, shared
+
+	monitor.scraper.listening.Set()
+	monitor.scraper.touchNotify(now.Add(-time.Second))
+
+	code, response := serveHealthTest(t, monitor, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthStatusFail, response.Status)
+	assert.Equal(t, healthStatusOK, response.Checks[healthCheckDatabase].Status)
+	assert.Equal(t, "connection refused", response.Checks[healthCheckSharedDatabase].Error)
+	assert.Equal(t, healthStatusOK, response.Checks[healthCheckListen].Status)
+	assert.Equal(t, healthStatusOK, response.Checks[healthCheckNotifyLag].Status)
+	assert.Equal(t, healthStatusOK, response.Checks[healthCheckAbi].Status)
+
+	shared.err = nil
+
+	code, response = serveHealthTest(t, monitor, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthStatusOK, response.Status)
+
+	monitor.scraper.touchNotify(now.Add(-monitor.config.health.notifyLag - time.Second))
+
+	code, response = serveHealthTest(t, monitor, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthStatusFail, response.Checks[healthCheckNotifyLag].Status)
+}
+
+func TestHealthAbiNotLoaded(t *testing.T) {
+	monitor := newTestMonitor(t)
+	monitor.abiDecoder = nil
+
+	assert.Equal(t, errAbiNotLoaded, monitor.checkAbi(context.Background()))
+}
+
+func TestHealthNotifyFilterLag(t *testing.T) {
+	now := time.Now()
+	monitor := newTestMonitor(t)
+	monitor.config.db.notifyFilter = true
+	db := &healthDatabase{head: now.Add(-time.Hour)}
+	monitor.pool = db
+
+	// the matching actions are rare, the chain head is not used
+	monitor.scraper.touchNotify(now.Add(-monitor.config.health.notifyLag - time.Second))
+	assert.NoError(t, monitor.checkNotifyLag(context.Background()))
+
+	db.head = now
+	assert.Error(t, monitor.checkNotifyLag(context.Background()))
+
+	db.err = pgx.ErrNoRows
+	assert.NoError(t, monitor.checkNotifyLag(context.Background()))
+}
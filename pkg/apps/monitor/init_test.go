@@ -1,10 +1,25 @@
 package monitor
 
 import (
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"testing"
 )
 
+var testLoggers *Loggers
+
 func init() {
 	logger, _ := zap.NewDevelopment()
-	EnableDebugLogging(logger)
+	testLoggers = NewLoggers(logger)
+}
+
+// newTestMonitor creates a monitor with the default config and mocked databases
+func newTestMonitor(t *testing.T) *Monitor {
+	config := newConfig()
+	config.skipTokenCheck = true
+
+	abiDecoder, err := NewAbiDecoder(config, testLoggers.Decoder)
+	require.NoError(t, err)
+
+	return NewMonitor(config, abiDecoder, &DatabaseMock{}, &DatabaseMock{}, testLoggers, nil)
 }
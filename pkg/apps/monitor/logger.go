@@ -1,28 +1,147 @@
 package monitor
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
 )
 
-var mainLog = zap.NewNop()
-var sessionLog = zap.NewNop()
-var scraperLog = zap.NewNop()
-var methodLog = zap.NewNop()
-var decoderLog = zap.NewNop()
+// Subsystems with own log level
+const (
+	logMain    = "main"
+	logSession = "session"
+	logScraper = "scraper"
+	logMethod  = "method"
+	logDecoder = "decoder"
+)
+
+var logSubsystems = []string{logMain, logSession, logScraper, logMethod, logDecoder}
+
+func isLogSubsystem(name string) bool {
+	for _, subsystem := range logSubsystems {
+		if subsystem == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Loggers holds a logger for every monitor subsystem
+type Loggers struct {
+	Main    *zap.Logger
+	Session *zap.Logger
+	Scraper *zap.Logger
+	Method  *zap.Logger
+	Decoder *zap.Logger
+
+	// levels changed at runtime, nil if the loggers are not created from the config
+	levels map[string]zap.AtomicLevel
+}
+
+// NewLoggers use one logger for all subsystems
+func NewLoggers(l *zap.Logger) *Loggers {
+	return &Loggers{
+		Main:    l,
+		Session: l,
+		Scraper: l,
+		Method:  l,
+		Decoder: l,
+	}
+}
+
+// NewLoggersFromConfig creates a logger with own level for every subsystem
+func NewLoggersFromConfig(config *Config) (*Loggers, error) {
+	loggers := &Loggers{levels: make(map[string]zap.AtomicLevel)}
+	for _, subsystem := range logSubsystems {
+		level, ok := config.log.levels[subsystem]
+		if !ok {
+			level = config.log.level
+		}
+
+		atomicLevel := zap.NewAtomicLevelAt(level)
+		logger, err := newSubsystemLogger(&config.log, atomicLevel)
+		if err != nil {
+			return nil, fmt.Errorf("%s logger error: %s", subsystem, err)
+		}
+		loggers.levels[subsystem] = atomicLevel
+
+		switch subsystem {
+		case logMain:
+			loggers.Main = logger
+		case logSession:
+			loggers.Session = logger
+		case logScraper:
+			loggers.Scraper = logger
+		case logMethod:
+			loggers.Method = logger
+		case logDecoder:
+			loggers.Decoder = logger
+		}
+	}
+
+	return loggers, nil
+}
+
+func newSubsystemLogger(config *LogConfig, level zap.AtomicLevel) (*zap.Logger, error) {
+	// the console format changes the encoding only, not the production behavior of the logger
+	zapConfig := zap.NewProductionConfig()
+	if config.format == logFormatConsole {
+		zapConfig.Encoding = logFormatConsole
+		zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+
+	zapConfig.Level = level
+	zapConfig.Sampling = nil
+	if config.sampling.initial > 0 {
+		zapConfig.Sampling = &zap.SamplingConfig{
+			Initial:    config.sampling.initial,
+			Thereafter: config.sampling.thereafter,
+		}
+	}
+
+	return zapConfig.Build()
+}
+
+func newNopLoggers() *Loggers {
+	return NewLoggers(zap.NewNop())
+}
+
+// Sync flushes the buffered logs
+func (l *Loggers) Sync() {
+	for _, logger := range []*zap.Logger{l.Main, l.Session, l.Scraper, l.Method, l.Decoder} {
+		_ = logger.Sync()
+	}
+}
 
-func EnableDebugLogging(l *zap.Logger) {
-	mainLog = l
-	sessionLog = l
-	scraperLog = l
-	methodLog = l
-	decoderLog = l
+// handleLogLevels registers GET /log/levels and GET, PUT /log/levels/{subsystem} with body {"level":"debug"}
+func (l *Loggers) handleLogLevels(router *mux.Router) {
+	if l.levels == nil {
+		return
+	}
+
+	router.HandleFunc("/log/levels", l.serveLogLevels).Methods("GET")
+	router.HandleFunc("/log/levels/{subsystem}", func(w http.ResponseWriter, r *http.Request) {
+		level, ok := l.levels[mux.Vars(r)["subsystem"]]
+		if !ok {
+			http.Error(w, "unknown subsystem", http.StatusNotFound)
+			return
+		}
+		level.ServeHTTP(w, r)
+	}).Methods("GET", "PUT")
 }
 
-func newLogger(production bool) (l *zap.Logger) {
-	if production {
-		l, _ = zap.NewProduction()
-	} else {
-		l, _ = zap.NewDevelopment()
+func (l *Loggers) serveLogLevels(w http.ResponseWriter, _ *http.Request) {
+	levels := make(map[string]zapcore.Level, len(l.levels))
+	for subsystem, level := range l.levels {
+		levels[subsystem] = level.Level()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(levels); err != nil {
+		l.Main.Error("log levels response error", zap.Error(err))
 	}
-	return
 }
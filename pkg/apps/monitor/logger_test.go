@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggersFromConfig(t *testing.T) {
+	config := newConfig()
+	config.log.level = zapcore.WarnLevel
+	config.log.levels[logSession] = zapcore.DebugLevel
+	config.log.sampling = LogSamplingConfig{100, 100}
+
+	loggers, err := NewLoggersFromConfig(config)
+	require.NoError(t, err)
+
+	assert.True(t, loggers.Session.Core().Enabled(zapcore.DebugLevel))
+	assert.False(t, loggers.Main.Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, loggers.Decoder.Core().Enabled(zapcore.WarnLevel))
+	assert.Len(t, loggers.levels, len(logSubsystems))
+}
+
+func TestNewLoggersConsoleFormat(t *testing.T) {
+	config := newConfig()
+	config.log.format = logFormatConsole
+
+	loggers, err := NewLoggersFromConfig(config)
+	require.NoError(t, err)
+
+	// the development logger panics on DPanic
+	assert.NotPanics(t, func() { loggers.Main.DPanic("console format test") })
+	assert.False(t, loggers.Main.Core().Enabled(zapcore.DebugLevel))
+}
+
+func TestLogLevelsHandler(t *testing.T) {
+	monitor := newTestMonitor(t)
+	monitor.config.admin.token = testAdminToken
+
+	loggers, err := NewLoggersFromConfig(monitor.config)
+	require.NoError(t, err)
+	monitor.log = loggers
+
+	handler := monitor.Handler()
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Authorization", "Bearer "+testAdminToken)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("PUT", "/admin/log/levels/scraper", `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, loggers.Scraper.Core().Enabled(zapcore.DebugLevel))
+	assert.False(t, loggers.Session.Core().Enabled(zapcore.DebugLevel))
+
+	recorder = serve("GET", "/admin/log/levels", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	levels := make(map[string]string)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &levels))
+	assert.Equal(t, "debug", levels[logScraper])
+	assert.Equal(t, "info", levels[logMain])
+
+	recorder = serve("PUT", "/admin/log/levels/unknown", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
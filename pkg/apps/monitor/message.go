@@ -1,33 +1,36 @@
 package monitor
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"github.com/DaoCasino/platform-action-monitor/pkg/protocol"
+)
 
-type RequestMessage struct {
-	ID     *string         `json:"id"`
-	Method *string         `json:"method"`
-	Params json.RawMessage `json:"params"`
-}
+type (
+	RequestMessage       = protocol.RequestMessage
+	ResponseErrorMessage = protocol.ResponseErrorMessage
+	EventMessage         = protocol.EventMessage
+)
 
-type ResponseErrorMessage struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
+// ResponseMessage is protocol.ResponseMessage with the response builders of the monitor
+type ResponseMessage protocol.ResponseMessage
 
-type ResponseMessage struct {
-	ID     *string               `json:"id"`
-	Result json.RawMessage       `json:"result"`
-	Error  *ResponseErrorMessage `json:"error"`
-}
-
-type EventMessage struct {
-	Offset uint64   `json:"offset"` // last event.offset
-	Events []*Event `json:"events"`
+// newEventMessage puts the synthetic events to the alerts, offset is the last event offset or the given offset without events
+func newEventMessage(events []*Event, offset uint64) *EventMessage {
+	message := &EventMessage{Offset: offset, Events: make([]*Event, 0, len(events))}
+	for _, event := range events {
+		if event.IsSynthetic() {
+			message.Alerts = append(message.Alerts, event)
+			continue
+		}
+		message.Events = append(message.Events, event)
+		message.Offset = event.Offset
+	}
+	return message
 }
 
-func newEventMessage(events []*Event) ([]byte, error) {
+func marshalEventMessage(message *EventMessage) ([]byte, error) {
 	response := newResponseMessage()
-	err := response.setResult(&EventMessage{events[len(events)-1].Offset, events})
-	if err != nil {
+	if err := response.setResult(message); err != nil {
 		return nil, err
 	}
 
@@ -18,6 +18,7 @@ const (
 	methodUnsubscribe      string = "unsubscribe"
 	methodBatchSubscribe   string = "batchSubscribe"
 	methodBatchUnsubscribe string = "batchUnsubscribe"
+	methodGetGame          string = "getGame"
 )
 
 func methodExecutorFactory(method string) (methodExecutor, error) {
@@ -31,6 +32,8 @@ func methodExecutorFactory(method string) (methodExecutor, error) {
 		params = new(methodBatchSubscribeParams)
 	case methodBatchUnsubscribe:
 		params = new(methodBatchUnsubscribeParams)
+	case methodGetGame:
+		params = new(methodGetGameParams)
 	default:
 		return nil, fmt.Errorf("method not found")
 	}
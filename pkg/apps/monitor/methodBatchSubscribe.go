@@ -16,15 +16,17 @@ func (p *methodBatchSubscribeParams) isValid() bool {
 }
 
 func (p *methodBatchSubscribeParams) execute(ctx context.Context, session *Session) (methodResult, error) {
-	methodLog.Debug("> batch subscribe",
-		zap.String("token", p.Token),
+	session.monitor.log.Method.Debug("> batch subscribe",
+		zap.String("token", tokenHashPrefix(p.Token)),
 		zap.Strings("topics", p.Topics),
 		zap.Uint64("offset", p.Offset),
 		zap.String("session.id", session.ID))
 
-	if err := checkToken(ctx, p.Token); err != nil {
+	user, err := session.monitor.authorize(ctx, p.Token, p.Topics...)
+	if err != nil {
 		return nil, err
 	}
+	session.setToken(p.Token, user)
 
 	scraperResponse := make(chan *ScraperResponseMessage)
 	for i, topic := range p.Topics {
@@ -37,7 +39,7 @@ func (p *methodBatchSubscribeParams) execute(ctx context.Context, session *Sessi
 			message.response = scraperResponse
 		}
 
-		scraper.subscribe <- message
+		session.monitor.scraper.subscribe <- message
 	}
 
 	response := <-scraperResponse
@@ -48,19 +50,19 @@ func (p *methodBatchSubscribeParams) execute(ctx context.Context, session *Sessi
 func (p *methodBatchSubscribeParams) after(ctx context.Context, session *Session) {
 	err := session.sendBatchEventsFromDatabase(ctx, p.Topics, p.Offset) // this block operation
 	if err != nil {
-		methodLog.Error("sendBatchEvents error", zap.Error(err), zap.String("session.ID", session.ID))
+		session.monitor.log.Method.Error("sendBatchEvents error", zap.Error(err), zap.String("session.ID", session.ID))
 		return
 	}
 
-	methodLog.Debug("sendBatchEvents done", zap.Uint64("session.offset", session.Offset()), zap.String("session.ID", session.ID))
+	session.monitor.log.Method.Debug("sendBatchEvents done", zap.Uint64("session.offset", session.Offset()), zap.String("session.ID", session.ID))
 
 	err = session.sendQueueMessages(ctx)
 	if err != nil {
-		methodLog.Error("sendQueueMessages error", zap.Error(err), zap.String("session.ID", session.ID))
+		session.monitor.log.Method.Error("sendQueueMessages error", zap.Error(err), zap.String("session.ID", session.ID))
 		return
 	}
 
-	methodLog.Debug("sendQueueMessages done, open queueMessages",
+	session.monitor.log.Method.Debug("sendQueueMessages done, open queueMessages",
 		zap.Int("queue len", len(session.queueMessages.events)),
 		zap.Uint64("session.offset", session.Offset()),
 		zap.String("session.ID", session.ID),
@@ -14,7 +14,7 @@ func (p *methodBatchUnsubscribeParams) isValid() bool {
 }
 
 func (p *methodBatchUnsubscribeParams) execute(_ context.Context, session *Session) (methodResult, error) {
-	methodLog.Debug("> batch unsubscribe", zap.Strings("topics", p.Topics), zap.String("session.id", session.ID))
+	session.monitor.log.Method.Debug("> batch unsubscribe", zap.Strings("topics", p.Topics), zap.String("session.id", session.ID))
 
 	scraperResponse := make(chan *ScraperResponseMessage)
 	for i, topic := range p.Topics {
@@ -27,13 +27,13 @@ func (p *methodBatchUnsubscribeParams) execute(_ context.Context, session *Sessi
 			message.response = scraperResponse
 		}
 
-		scraper.unsubscribe <- message
+		session.monitor.scraper.unsubscribe <- message
 	}
 
 	response := <-scraperResponse
 	return response.result, response.err
 }
 
-func (p *methodBatchUnsubscribeParams) after(_ context.Context, _ *Session) {
-	methodLog.Debug("after batch unsubscribe")
+func (p *methodBatchUnsubscribeParams) after(_ context.Context, session *Session) {
+	session.monitor.log.Method.Debug("after batch unsubscribe")
 }
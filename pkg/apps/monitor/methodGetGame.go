@@ -0,0 +1,37 @@
+package monitor
+
+import (
+	"context"
+	"go.uber.org/zap"
+)
+
+type methodGetGameParams struct {
+	Token string `json:"token"`
+	// empty for the default source
+	Source   string  `json:"source"`
+	CasinoID *uint64 `json:"casino_id"`
+	GameID   *uint64 `json:"game_id"`
+}
+
+func (p *methodGetGameParams) isValid() bool {
+	return p.Token != "" && p.CasinoID != nil && p.GameID != nil
+}
+
+func (p *methodGetGameParams) execute(ctx context.Context, session *Session) (methodResult, error) {
+	session.monitor.log.Method.Debug("> getGame",
+		zap.String("token", tokenHashPrefix(p.Token)),
+		zap.String("source", p.Source),
+		zap.Uint64("casino_id", *p.CasinoID),
+		zap.Uint64("game_id", *p.GameID),
+		zap.String("session.id", session.ID))
+
+	if err := session.monitor.checkToken(ctx, p.Token); err != nil {
+		return nil, err
+	}
+
+	return session.monitor.games.get(ctx, p.Source, *p.CasinoID, *p.GameID)
+}
+
+func (p *methodGetGameParams) after(_ context.Context, session *Session) {
+	session.monitor.log.Method.Debug("after getGame")
+}
@@ -0,0 +1,28 @@
+package monitor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMethodGetGame(t *testing.T) {
+	session, teardownTestCase := setupSessionTestCase(t)
+	defer teardownTestCase(t)
+
+	ctx := context.Background()
+	session.monitor.games.add(ctx, &Event{CasinoID: 1, GameID: 2, EventType: EventGameStarted})
+
+	casinoID, gameID := uint64(1), uint64(2)
+	getGame := &methodGetGameParams{Token: "123", CasinoID: &casinoID, GameID: &gameID}
+	require.True(t, getGame.isValid())
+
+	result, err := getGame.execute(ctx, session)
+	require.NoError(t, err)
+	assert.Equal(t, GameStateStarted, result.(*Game).State)
+
+	gameID = 3
+	_, err = getGame.execute(ctx, session)
+	assert.Equal(t, errGameNotFound, err)
+}
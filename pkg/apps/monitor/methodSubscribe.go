@@ -16,15 +16,17 @@ func (p *methodSubscribeParams) isValid() bool {
 }
 
 func (p *methodSubscribeParams) execute(ctx context.Context, session *Session) (methodResult, error) {
-	methodLog.Debug("> subscribe",
-		zap.String("token", p.Token),
+	session.monitor.log.Method.Debug("> subscribe",
+		zap.String("token", tokenHashPrefix(p.Token)),
 		zap.String("topic", p.Topic),
 		zap.Uint64("offset", p.Offset),
 		zap.String("session.id", session.ID))
 
-	if err := checkToken(ctx, p.Token); err != nil {
+	user, err := session.monitor.authorize(ctx, p.Token, p.Topic)
+	if err != nil {
 		return nil, err
 	}
+	session.setToken(p.Token, user)
 
 	message := &ScraperSubscribeMessage{
 		name:     p.Topic,
@@ -33,7 +35,7 @@ func (p *methodSubscribeParams) execute(ctx context.Context, session *Session) (
 	}
 
 	session.setOffset(p.Offset)
-	scraper.subscribe <- message
+	session.monitor.scraper.subscribe <- message
 	response := <-message.response
 	return response.result, response.err
 }
@@ -42,19 +44,19 @@ func (p *methodSubscribeParams) execute(ctx context.Context, session *Session) (
 func (p *methodSubscribeParams) after(ctx context.Context, session *Session) {
 	err := session.sendEventsFromDatabase(ctx, p.Topic, p.Offset) // this block operation
 	if err != nil {
-		methodLog.Error("sendEvents error", zap.Error(err), zap.String("session.ID", session.ID))
+		session.monitor.log.Method.Error("sendEvents error", zap.Error(err), zap.String("session.ID", session.ID))
 		return
 	}
 
-	methodLog.Debug("sendEvents done", zap.Uint64("session.offset", session.Offset()), zap.String("session.ID", session.ID))
+	session.monitor.log.Method.Debug("sendEvents done", zap.Uint64("session.offset", session.Offset()), zap.String("session.ID", session.ID))
 
 	err = session.sendQueueMessages(ctx)
 	if err != nil {
-		methodLog.Error("sendQueueMessages error", zap.Error(err), zap.String("session.ID", session.ID))
+		session.monitor.log.Method.Error("sendQueueMessages error", zap.Error(err), zap.String("session.ID", session.ID))
 		return
 	}
 
-	methodLog.Debug("sendQueueMessages done, open queueMessages",
+	session.monitor.log.Method.Debug("sendQueueMessages done, open queueMessages",
 		zap.Int("queue len", len(session.queueMessages.events)),
 		zap.Uint64("session.offset", session.Offset()),
 		zap.String("session.ID", session.ID),
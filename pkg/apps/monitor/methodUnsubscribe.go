@@ -14,7 +14,7 @@ func (p *methodUnsubscribeParams) isValid() bool {
 }
 
 func (p *methodUnsubscribeParams) execute(_ context.Context, session *Session) (methodResult, error) {
-	methodLog.Debug("> unsubscribe", zap.String("topic", p.Topic), zap.String("session.id", session.ID))
+	session.monitor.log.Method.Debug("> unsubscribe", zap.String("topic", p.Topic), zap.String("session.id", session.ID))
 
 	message := &ScraperUnsubscribeMessage{
 		name:     p.Topic,
@@ -22,12 +22,12 @@ func (p *methodUnsubscribeParams) execute(_ context.Context, session *Session) (
 		response: make(chan *ScraperResponseMessage),
 	}
 
-	scraper.unsubscribe <- message
+	session.monitor.scraper.unsubscribe <- message
 	response := <-message.response
 
 	return response.result, response.err
 }
 
-func (p *methodUnsubscribeParams) after(_ context.Context, _ *Session) {
-	methodLog.Debug("after unsubscribe")
+func (p *methodUnsubscribeParams) after(_ context.Context, session *Session) {
+	session.monitor.log.Method.Debug("after unsubscribe")
 }
@@ -26,4 +26,8 @@ func TestMethodExecutorFactory(t *testing.T) {
 	m, err = methodExecutorFactory(methodBatchUnsubscribe)
 	require.NoError(t, err)
 	assert.IsType(t, &methodBatchUnsubscribeParams{}, m)
+
+	m, err = methodExecutorFactory(methodGetGame)
+	require.NoError(t, err)
+	assert.IsType(t, &methodGetGameParams{}, m)
 }
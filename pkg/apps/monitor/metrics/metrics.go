@@ -4,25 +4,184 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"time"
 )
 
-var (
-	EventsTotal = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "events_total",
-		})
+// Metrics of a monitor, every monitor has its own collectors,
+// the monitors of one registry are told apart by prometheus.WrapRegistererWith
+type Metrics struct {
+	EventsTotal                   prometheus.Counter
+	UsersOnline                   prometheus.Gauge
+	GamesStuck                    *prometheus.GaugeVec
+	EventDeliverySeconds          *prometheus.HistogramVec
+	NotifyToBroadcastSeconds      prometheus.Histogram
+	SignidicePart1ToPart2Seconds  *prometheus.HistogramVec
+	SignidicePart2ToFinishSeconds *prometheus.HistogramVec
+	TopicSubscribers              *prometheus.GaugeVec
+	SessionSendBufferDepth        prometheus.Histogram
+	BroadcastSeconds              prometheus.Histogram
+	ReplayEvents                  prometheus.Histogram
+	QuerySeconds                  *prometheus.HistogramVec
+	PoolConns                     *prometheus.GaugeVec
+	PoolAcquireTotal              *prometheus.CounterVec
+	DecodeErrorsTotal             *prometheus.CounterVec
+	TokenChecksTotal              *prometheus.CounterVec
+	WebsocketCloseTotal           *prometheus.CounterVec
+	NotificationsTotal            *prometheus.CounterVec
+}
 
-	UsersOnline = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "users_online",
-		})
-)
+// New creates the metrics and registers them on the registerer, nil registerer keeps them unregistered
+func New(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		EventsTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "events_total",
+			}),
+
+		UsersOnline: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "users_online",
+			}),
+
+		GamesStuck: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "games_stuck",
+				Help: "Games staying in the state longer than the stuck timeout",
+			}, []string{"state"}),
+
+		EventDeliverySeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "event_delivery_seconds",
+				Help:    "Time from the event block production to the websocket write",
+				Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+			}, []string{"source"}),
+
+		NotifyToBroadcastSeconds: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "notify_to_broadcast_seconds",
+				Help:    "Time from the database notification to the broadcast to the topic sessions",
+				Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+			}),
+
+		SignidicePart1ToPart2Seconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "signidice_part1_to_part2_seconds",
+				Help:    "Block time between signidice_part_1_request and signidice_part_2_request",
+				Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+			}, []string{"casino_id"}),
+
+		SignidicePart2ToFinishSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "signidice_part2_to_finish_seconds",
+				Help:    "Block time between signidice_part_2_request and game_finished of the same req_id",
+				Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+			}, []string{"casino_id"}),
+
+		TopicSubscribers: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "topic_subscribers",
+				Help: "Sessions subscribed to the topic",
+			}, []string{"topic"}),
+
+		SessionSendBufferDepth: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "session_send_buffer_depth",
+				Help:    "Messages waiting in the session send buffer when a message is added, over all sessions",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+			}),
+
+		BroadcastSeconds: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "broadcast_seconds",
+				Help:    "Time to pass an event to every topic session",
+				Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16),
+			}),
+
+		ReplayEvents: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "replay_events",
+				Help:    "Events sent from the database after subscribe",
+				Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+			}),
+
+		QuerySeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "query_seconds",
+				Help:    "SQL query duration",
+				Buckets: prometheus.ExponentialBuckets(0.0005, 2, 15),
+			}, []string{"query"}),
+
+		PoolConns: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pool_conns",
+				Help: "Database pool connections by state: total, idle, acquired, constructing, max",
+			}, []string{"pool", "state"}),
+
+		PoolAcquireTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "pool_acquire_total",
+				Help: "Database pool acquires",
+			}, []string{"pool"}),
+
+		DecodeErrorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "decode_errors_total",
+				Help: "act_data decode errors by event type",
+			}, []string{"event_type"}),
+
+		TokenChecksTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "token_checks_total",
+				Help: "Token checks by result: ok, rejected, error, skipped",
+			}, []string{"result"}),
+
+		WebsocketCloseTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "websocket_close_total",
+				Help: "Closed websocket sessions by reason",
+			}, []string{"reason"}),
+
+		NotificationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "notifications_total",
+				Help: "Database notifications by result: fetched, discarded, invalid",
+			}, []string{"result"}),
+	}
+
+	if registerer != nil {
+		registerer.MustRegister(
+			m.EventsTotal,
+			m.UsersOnline,
+			m.GamesStuck,
+			m.SignidicePart1ToPart2Seconds,
+			m.SignidicePart2ToFinishSeconds,
+			m.EventDeliverySeconds,
+			m.NotifyToBroadcastSeconds,
+			m.TopicSubscribers,
+			m.SessionSendBufferDepth,
+			m.BroadcastSeconds,
+			m.ReplayEvents,
+			m.QuerySeconds,
+			m.PoolConns,
+			m.PoolAcquireTotal,
+			m.DecodeErrorsTotal,
+			m.TokenChecksTotal,
+			m.WebsocketCloseTotal,
+			m.NotificationsTotal,
+		)
+	}
+	return m
+}
 
-func init() {
-	prometheus.MustRegister(EventsTotal)
-	prometheus.MustRegister(UsersOnline)
+// ObserveQuery observes the query duration, nil metrics are not observed
+func (m *Metrics) ObserveQuery(query string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.QuerySeconds.WithLabelValues(query).Observe(time.Since(start).Seconds())
 }
 
-func Handle(router *mux.Router) {
-	router.Handle("/metrics", promhttp.Handler())
+// Handle serves the metrics of the gatherer on /metrics
+func Handle(router *mux.Router, gatherer prometheus.Gatherer) {
+	router.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
 }
@@ -5,74 +5,172 @@ import (
 	"fmt"
 	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tevino/abool"
 	"net/http"
 )
 
-// Globals
-var (
-	config         *Config
-	abiDecoder     *AbiDecoder
-	pool           *pgxpool.Pool
-	sharedPool     *pgxpool.Pool
+// Monitor is a self-contained action monitor instance,
+// several monitors can live in one process
+type Monitor struct {
+	config     *Config
+	abiDecoder *AbiDecoder
+	pool       DatabasePool
+	sharedPool DatabasePool
+	log        *Loggers
+	metrics    *metrics.Metrics
+	// serves /metrics, nil if the registerer is not a gatherer
+	gatherer prometheus.Gatherer
+
 	scraper        *Scraper
 	sessionManager *SessionManager
-)
+	games          *GameTracker
+	upgrader       websocket.Upgrader
+
+	// set by Shutdown, the monitor does not accept new connections
+	shutdown *abool.AtomicBool
+	// running sendChunked calls waited by Shutdown
+	deliveries *inflight
+
+	// context of all monitor goroutines, canceled by Close or when the Run context is done
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewMonitor creates a monitor; the monitor owns the pools and closes them in Close.
+// The monitor listens for notifications only if pool is a DatabaseListener (eg *pgxpool.Pool).
+// The metrics are registered on registerer, the monitors sharing it need distinct labels
+// (prometheus.WrapRegistererWith); nil registerer is a new registry of the monitor.
+func NewMonitor(config *Config, abiDecoder *AbiDecoder, pool DatabasePool, sharedPool DatabasePool, loggers *Loggers, registerer prometheus.Registerer) *Monitor {
+	if loggers == nil {
+		loggers = newNopLoggers()
+	}
+	if registerer == nil {
+		registerer = prometheus.NewRegistry()
+	}
+	gatherer, _ := registerer.(prometheus.Gatherer)
+
+	m := &Monitor{
+		config:     config,
+		abiDecoder: abiDecoder,
+		pool:       pool,
+		sharedPool: sharedPool,
+		log:        loggers,
+		metrics:    metrics.New(registerer),
+		gatherer:   gatherer,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  config.upgrader.readBufferSize,
+			WriteBufferSize: config.upgrader.writeBufferSize,
+			CheckOrigin:     func(r *http.Request) bool { return true },
+		},
+		shutdown:   abool.New(),
+		deliveries: new(inflight),
+	}
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.scraper = newScraper(m)
+	m.sessionManager = newSessionManager(m)
+	m.games = newGameTracker(m) // after scraper
 
-func Init(configFile *string, parentContext context.Context) (*http.Server, func(), error) {
-	logger := newLogger(false)
-	EnableDebugLogging(logger)
+	return m
+}
+
+// Handler returns the http handler with websocket, games, ping, health, admin and metrics endpoints
+func (m *Monitor) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/", m.serveWs)
+	router.HandleFunc("/games/{casino_id}/{game_id}", m.serveGame).Methods("GET")
+	router.HandleFunc("/healthz", m.serveHealth(m.livenessCheckers())).Methods("GET")
+	router.HandleFunc("/readyz", m.serveHealth(m.readinessCheckers())).Methods("GET")
+
+	router.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(200)
+	})
+
+	m.handleAdmin(router)
+	if m.gatherer != nil {
+		metrics.Handle(router, m.gatherer)
+	}
+
+	return router
+}
 
-	var err error
-	config = newConfig()
-	if *configFile != "" {
-		err = config.loadFromFile(configFile)
-		if err != nil {
-			return nil, nil, fmt.Errorf("config file error: %s", err.Error())
+// Run blocks until parentContext is done or the monitor is closed
+func (m *Monitor) Run(parentContext context.Context) {
+	m.log.Main.Info("monitor started")
+	defer m.log.Main.Info("monitor stopped")
+
+	go func() {
+		select {
+		case <-parentContext.Done():
+			m.cancel()
+		case <-m.ctx.Done():
 		}
+	}()
+
+	go m.sessionManager.run(m.ctx)
+	go m.games.run(m.ctx)
+	go m.runPoolStats(m.ctx)
+	m.scraper.run(m.ctx)
+}
+
+// Close stops the monitor goroutines and closes the database pools, use Shutdown to close the sessions gracefully
+func (m *Monitor) Close() {
+	m.cancel()
+
+	if m.pool != nil {
+		m.pool.Close()
 	}
 
-	abiDecoder, err = newAbiDecoder(&config.abi)
-	if err != nil {
-		return nil, nil, fmt.Errorf("abi decoder error: %s", err.Error())
+	if m.sharedPool != nil {
+		m.sharedPool.Close()
 	}
+}
 
-	pool, err = pgxpool.Connect(parentContext, config.db.url)
+// Init creates the monitor from the config file, starts it and returns the http server and the monitor shutdown
+func Init(configFile *string, parentContext context.Context) (*http.Server, func(context.Context) error, error) {
+	config, err := LoadConfig(*configFile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("database connection error: %s", err.Error())
+		return nil, nil, fmt.Errorf("config file error: %s", err.Error())
 	}
 
-	sharedPool, err = pgxpool.Connect(parentContext, config.sharedDatabase.url)
+	loggers, err := NewLoggersFromConfig(config)
 	if err != nil {
-		return nil, nil, fmt.Errorf("shared database connection error: %s", err.Error())
+		return nil, nil, fmt.Errorf("logger error: %s", err.Error())
 	}
 
-	scraper = newScraper()
-	sessionManager = newSessionManager()
+	abiDecoder, err := NewAbiDecoder(config, loggers.Decoder)
+	if err != nil {
+		return nil, nil, fmt.Errorf("abi decoder error: %s", err.Error())
+	}
 
-	router := mux.NewRouter()
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(parentContext, scraper, w, r)
-	})
+	var pool, sharedPool DatabasePool
+	if config.replay.file == "" && config.generator.rate > 0 {
+		// the generated actions are kept in memory, the config validation requires skipTokenCheck
+		loggers.Main.Warn("generator mode: the databases are in memory, the tokens are not checked")
+		db := NewDatabaseMemory()
+		pool, sharedPool = db, db
+	} else {
+		if pool, err = pgxpool.Connect(parentContext, config.db.url); err != nil {
+			return nil, nil, fmt.Errorf("database connection error: %s", err.Error())
+		}
 
-	router.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(200)
-	})
+		if sharedPool, err = pgxpool.Connect(parentContext, config.sharedDatabase.url); err != nil {
+			pool.Close()
+			return nil, nil, fmt.Errorf("shared database connection error: %s", err.Error())
+		}
+	}
 
-	metrics.Handle(router)
+	m := NewMonitor(config, abiDecoder, pool, sharedPool, loggers, prometheus.DefaultRegisterer)
 
 	srv := &http.Server{
 		Addr:    config.serverAddress,
-		Handler: router,
+		Handler: m.Handler(),
 	}
 
-	go sessionManager.run(parentContext)
-	go scraper.run(parentContext)
-
-	closeFunc := func() {
-		pool.Close()
-		sharedPool.Close()
-	}
+	go m.Run(parentContext)
 
-	return srv, closeFunc, nil
+	return srv, m.Shutdown, nil
 }
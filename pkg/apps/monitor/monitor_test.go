@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMonitorHandlerPing(t *testing.T) {
+	monitor := newTestMonitor(t)
+
+	recorder := httptest.NewRecorder()
+	monitor.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/ping", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestMonitorRunClose(t *testing.T) {
+	first := newTestMonitor(t)
+	second := newTestMonitor(t)
+
+	done := make(chan struct{})
+	go func() {
+		first.Run(context.Background())
+		close(done)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go second.Run(ctx)
+
+	first.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("monitor run not stopped after close")
+	}
+
+	// the second monitor is still running
+	session := newSession(second, nil)
+	message := &ScraperSubscribeMessage{name: "test", session: session, response: make(chan *ScraperResponseMessage)}
+	second.scraper.subscribe <- message
+	response := <-message.response
+	assert.Equal(t, true, response.result)
+}
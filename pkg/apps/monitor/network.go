@@ -4,36 +4,31 @@ import (
 	"context"
 	"fmt"
 	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
+	"time"
 )
 
 // TODO: need remove and replace to sendBatchEventsFromDatabase
 // call from readPump it is blocked function
 func (s *Session) sendEventsFromDatabase(parentContext context.Context, topic string, offset uint64) error {
-	sessionLog.Debug("after subscribe send events", zap.String("session.id", s.ID), zap.Uint64("offset", offset))
+	s.log.Debug("after subscribe send events", zap.String("session.id", s.ID), zap.Uint64("offset", offset))
 
-	eventType, err := getEventTypeFromTopic(topic)
-	if err != nil {
-		return fmt.Errorf("get event type error: %s", err)
+	if isSyntheticTopic(topic) { // nothing to send from database
+		return nil
 	}
 
-	var conn *pgxpool.Conn
-	conn, err = pool.Acquire(parentContext)
+	source, name := parseTopic(topic)
+	eventType, err := getEventTypeFromTopic(name)
 	if err != nil {
-		return fmt.Errorf("pool acquire connection error: %s", err)
+		return fmt.Errorf("get event type error: %s", err)
 	}
 
-	defer func() {
-		conn.Release()
-	}()
-
-	events, err := fetchAllEvents(parentContext, conn.Conn(), offset, 0) // TODO: may be need count
+	events, err := s.monitor.fetchAllEvents(parentContext, s.monitor.pool, offset, 0) // TODO: may be need count
 	if err != nil {
 		return fmt.Errorf("fetch all events error: %s", err)
 	}
 
-	sessionLog.Debug("fetchAllEvents",
+	s.log.Debug("fetchAllEvents",
 		zap.Uint64("offset", offset),
 		zap.Int("events.len", len(events)),
 		zap.String("session.id", s.ID))
@@ -41,9 +36,10 @@ func (s *Session) sendEventsFromDatabase(parentContext context.Context, topic st
 	if len(events) == 0 {
 		return nil
 	}
-	filteredEvents := filterEventsByEventType(events, eventType)
+	filteredEvents := filterEventsByEventType(filterEventsBySource(events, source), eventType)
+	s.monitor.metrics.ReplayEvents.Observe(float64(len(filteredEvents)))
 
-	sessionLog.Debug("filterEventsByEventType",
+	s.log.Debug("filterEventsByEventType",
 		zap.Int("eventType", eventType),
 		zap.Int("filteredEvents.len", len(filteredEvents)),
 		zap.String("session.id", s.ID))
@@ -52,7 +48,7 @@ func (s *Session) sendEventsFromDatabase(parentContext context.Context, topic st
 		return nil
 	}
 
-	err = s.sendChunked(parentContext, filteredEvents) // blocked !
+	err = s.sendChunked(parentContext, filteredEvents, deliveryReplay) // blocked !
 	if err != nil {
 		return fmt.Errorf("sendChunked error: %s", err)
 	}
@@ -62,32 +58,31 @@ func (s *Session) sendEventsFromDatabase(parentContext context.Context, topic st
 
 // call from readPump it is blocked function
 func (s *Session) sendBatchEventsFromDatabase(parentContext context.Context, topics []string, offset uint64) error {
-	sessionLog.Debug("after subscribe send events", zap.String("session.id", s.ID), zap.Uint64("offset", offset))
+	s.log.Debug("after subscribe send events", zap.String("session.id", s.ID), zap.Uint64("offset", offset))
 
-	eventTypes := make([]int, len(topics))
-	for i, topic := range topics {
-		eventType, err := getEventTypeFromTopic(topic)
-		if err != nil {
+	eventTopics := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if isSyntheticTopic(topic) {
+			continue
+		}
+
+		_, name := parseTopic(topic)
+		if _, err := getEventTypeFromTopic(name); err != nil {
 			return fmt.Errorf("get event type error: %s", err)
 		}
-		eventTypes[i] = eventType
+		eventTopics = append(eventTopics, topic)
 	}
 
-	conn, err := pool.Acquire(parentContext)
-	if err != nil {
-		return fmt.Errorf("pool acquire connection error: %s", err)
+	if len(eventTopics) == 0 {
+		return nil
 	}
 
-	defer func() {
-		conn.Release()
-	}()
-
-	events, err := fetchAllEvents(parentContext, conn.Conn(), offset, 0) // TODO: may be need count
+	events, err := s.monitor.fetchAllEvents(parentContext, s.monitor.pool, offset, 0) // TODO: may be need count
 	if err != nil {
 		return fmt.Errorf("fetch all events error: %s", err)
 	}
 
-	sessionLog.Debug("fetchAllEvents",
+	s.log.Debug("fetchAllEvents",
 		zap.Uint64("offset", offset),
 		zap.Int("events.len", len(events)),
 		zap.String("session.id", s.ID))
@@ -95,10 +90,11 @@ func (s *Session) sendBatchEventsFromDatabase(parentContext context.Context, top
 	if len(events) == 0 {
 		return nil
 	}
-	filteredEvents := filterEventsByEventTypes(events, eventTypes)
+	filteredEvents := filterEventsByTopics(events, eventTopics)
+	s.monitor.metrics.ReplayEvents.Observe(float64(len(filteredEvents)))
 
-	sessionLog.Debug("filterEventsByEventTypes",
-		zap.Ints("eventTypes", eventTypes),
+	s.log.Debug("filterEventsByTopics",
+		zap.Strings("topics", eventTopics),
 		zap.Int("filteredEvents.len", len(filteredEvents)),
 		zap.String("session.id", s.ID))
 
@@ -106,7 +102,7 @@ func (s *Session) sendBatchEventsFromDatabase(parentContext context.Context, top
 		return nil
 	}
 
-	err = s.sendChunked(parentContext, filteredEvents) // blocked !
+	err = s.sendChunked(parentContext, filteredEvents, deliveryReplay) // blocked !
 	if err != nil {
 		return fmt.Errorf("sendChunked error: %s", err)
 	}
@@ -114,10 +110,18 @@ func (s *Session) sendBatchEventsFromDatabase(parentContext context.Context, top
 	return nil
 }
 
+// Source label of the delivery latency metric
+const (
+	deliveryLive   = "live"
+	deliveryReplay = "replay"
+)
+
 // blocked function, do not call in writePump
-func (s *Session) sendChunked(parentContext context.Context, events []*Event) error {
-	chunkSize := config.session.maxEventsInMessage
-	var offset uint64
+func (s *Session) sendChunked(parentContext context.Context, events []*Event, source string) error {
+	s.monitor.deliveries.add()
+	defer s.monitor.deliveries.done()
+
+	chunkSize := s.monitor.config.session.maxEventsInMessage
 
 loop:
 	for i := 0; i < len(events); i += chunkSize {
@@ -133,16 +137,18 @@ loop:
 			break
 		}
 
-		eventMessage, err := newEventMessage(sendEvents)
+		eventMessage := newEventMessage(sendEvents, s.Offset())
+		raw, err := marshalEventMessage(eventMessage)
 		if err != nil {
 			return err
 		}
 
-		data := newSendData(eventMessage)
+		data := newSendData(raw)
+		s.observeSendBuffer()
 
 		select {
 		case <-parentContext.Done():
-			sessionLog.Debug("sendChunked parent context done", zap.String("session.id", s.ID))
+			s.log.Debug("sendChunked parent context done", zap.String("session.id", s.ID))
 			break loop
 		case s.send <- data:
 			<-data.done // TODO: <- block! do not call in writePump
@@ -151,9 +157,9 @@ loop:
 				return data.err
 			}
 
-			offset = sendEvents[len(sendEvents)-1].Offset
-			s.setOffset(offset)
-			metrics.EventsTotal.Add(float64(len(sendEvents)))
+			s.setOffset(eventMessage.Offset)
+			s.monitor.metrics.EventsTotal.Add(float64(len(sendEvents)))
+			observeDelivery(s.monitor.metrics, sendEvents, source)
 		}
 	}
 
@@ -164,16 +170,31 @@ loop:
 func (s *Session) sendQueueMessages(parentContext context.Context) error {
 	s.queueMessages.Lock()
 	defer func() {
-		s.queueMessages.events = filterEventsFromOffset(s.queueMessages.events, s.Offset())
 		s.queueMessages.open()
 		s.queueMessages.Unlock()
 	}()
 
 	events := filterEventsFromOffset(s.queueMessages.events, s.Offset())
+	s.queueMessages.events = events
 
 	if len(events) == 0 {
 		return nil
 	}
 
-	return s.sendChunked(parentContext, events)
+	if err := s.sendChunked(parentContext, events, deliveryLive); err != nil {
+		return err
+	}
+
+	s.queueMessages.events = events[:0]
+	return nil
+}
+
+// observeDelivery measures the chain events, the alerts carry the block time of an older event
+func observeDelivery(observer *metrics.Metrics, events []*Event, source string) {
+	now := time.Now()
+	for _, event := range events {
+		if !event.BlockTime.IsZero() && !event.IsSynthetic() {
+			observer.EventDeliverySeconds.WithLabelValues(source).Observe(now.Sub(event.BlockTime).Seconds())
+		}
+	}
 }
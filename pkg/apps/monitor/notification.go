@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result label of the notifications metric
+const (
+	notificationFetched   = "fetched"
+	notificationDiscarded = "discarded"
+	notificationInvalid   = "invalid"
+)
+
+// timeout of deleting the notify filters of the channel on the listen stop
+const notifyFilterCleanupTimeout = 5 * time.Second
+
+const (
+	sqlDeleteNotifyFilter = "DELETE FROM chain.action_trace_notify_filter WHERE channel = $1"
+	sqlInsertNotifyFilter = "INSERT INTO chain.action_trace_notify_filter (channel, act_account, act_name) VALUES ($1, $2, $3)"
+)
+
+// ActionNotification is the payload of action_trace_notify_trigger:
+// {"offset":1,"act_account":"casino","act_name":"send"} or the offset only before the notify filter migration
+type ActionNotification struct {
+	Offset     uint64 `json:"offset"`
+	ActAccount string `json:"act_account"`
+	ActName    string `json:"act_name"`
+	// the payload has the account and the name of the action
+	hasAction bool
+}
+
+func parseNotification(payload string) (*ActionNotification, error) {
+	if !strings.HasPrefix(payload, "{") {
+		offset, err := strconv.ParseUint(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse offset error: %s", err)
+		}
+		return &ActionNotification{Offset: offset}, nil
+	}
+
+	notification := new(ActionNotification)
+	if err := json.Unmarshal([]byte(payload), notification); err != nil {
+		return nil, fmt.Errorf("parse payload error: %s", err)
+	}
+	notification.hasAction = true
+	return notification, nil
+}
+
+// match returns false if the action of the notification is not in the sources,
+// the notification without the action has to be fetched
+func (n *ActionNotification) match(sources []*SourceConfig) bool {
+	if !n.hasAction {
+		return true
+	}
+	_, ok := sourceOf(sources, n.ActAccount, n.ActName)
+	return ok
+}
+
+// syncNotifyFilter replaces the notify filters of the channel with the source filters,
+// without the filters the actions of the channel are notified to new_action_trace again
+func syncNotifyFilter(ctx context.Context, conn *pgx.Conn, channel string, filters []DatabaseFilters) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, sqlDeleteNotifyFilter, channel); err != nil {
+		return err
+	}
+
+	for _, filter := range filters {
+		if _, err = tx.Exec(ctx, sqlInsertNotifyFilter, channel, filter.actAccount, filter.actName); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
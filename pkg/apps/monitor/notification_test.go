@@ -0,0 +1,37 @@
+package monitor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseNotification(t *testing.T) {
+	notification, err := parseNotification("123")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123), notification.Offset)
+	assert.False(t, notification.hasAction)
+
+	notification, err = parseNotification(`{"offset":18446744073709551615,"act_account":"casino","act_name":"send"}`)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(18446744073709551615), notification.Offset)
+	assert.Equal(t, "casino", notification.ActAccount)
+	assert.Equal(t, "send", notification.ActName)
+	assert.True(t, notification.hasAction)
+
+	_, err = parseNotification("offset")
+	assert.Error(t, err)
+
+	_, err = parseNotification(`{"offset":"1"}`)
+	assert.Error(t, err)
+}
+
+func TestNotificationMatch(t *testing.T) {
+	casino, send := "casino", "send"
+	sources := []*SourceConfig{{name: "casino", filter: DatabaseFilters{actAccount: &casino, actName: &send}}}
+
+	assert.True(t, (&ActionNotification{Offset: 1}).match(sources))
+	assert.True(t, (&ActionNotification{1, "casino", "send", true}).match(sources))
+	assert.False(t, (&ActionNotification{1, "eosio", "onblock", true}).match(sources))
+	assert.True(t, (&ActionNotification{1, "eosio", "onblock", true}).match([]*SourceConfig{{}}))
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// ActionRecord is a line of the record file, the fetched action with the time of its notification
+type ActionRecord struct {
+	Offset     uint64         `json:"offset"`
+	ActAccount string         `json:"act_account"`
+	ActName    string         `json:"act_name"`
+	BlockTime  time.Time      `json:"block_time"`
+	ActData    EventDataSlice `json:"act_data"`
+	// the replay keeps the intervals between the notifications
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+func newActionRecord(rows *ActionTraceRows, notifiedAt time.Time) *ActionRecord {
+	return &ActionRecord{rows.offset, rows.actAccount, rows.actName, rows.blockTime, rows.actData, notifiedAt}
+}
+
+func (r *ActionRecord) rows() *ActionTraceRows {
+	return &ActionTraceRows{r.ActData, r.Offset, r.BlockTime, r.ActAccount, r.ActName}
+}
+
+// Recorder appends the actions to the record file as JSON lines
+type Recorder struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func NewRecorder(filename string) (*Recorder, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	return &Recorder{file, writer, json.NewEncoder(writer)}, nil
+}
+
+// Record writes the action, the line is flushed to keep the file complete on crash
+func (r *Recorder) Record(record *ActionRecord) error {
+	if err := r.encoder.Encode(record); err != nil {
+		return err
+	}
+	return r.writer.Flush()
+}
+
+func (r *Recorder) Close() error {
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// Player reads the record file and waits between the actions like between the recorded notifications
+type Player struct {
+	decoder *json.Decoder
+	// 2 is twice faster than recorded, 0 without delays
+	speed float64
+	last  time.Time
+	sleep func(time.Duration) <-chan time.Time
+}
+
+func NewPlayer(reader io.Reader, speed float64) *Player {
+	return &Player{decoder: json.NewDecoder(reader), speed: speed, sleep: time.After}
+}
+
+// Next returns the next action after the delay, io.EOF at the end of the record
+func (p *Player) Next(ctx context.Context) (*ActionRecord, error) {
+	record := new(ActionRecord)
+	if err := p.decoder.Decode(record); err != nil {
+		return nil, err
+	}
+
+	if p.speed > 0 && !p.last.IsZero() && record.NotifiedAt.After(p.last) {
+		delay := time.Duration(float64(record.NotifiedAt.Sub(p.last)) / p.speed)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.sleep(delay):
+		}
+	}
+	p.last = record.NotifiedAt
+	return record, nil
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestRecord(t *testing.T, filename string, actions []*ActionTraceRows, interval time.Duration) {
+	recorder, err := NewRecorder(filename)
+	require.NoError(t, err)
+
+	notifiedAt := time.Date(2020, 10, 19, 12, 0, 0, 0, time.UTC)
+	for i, action := range actions {
+		require.NoError(t, recorder.Record(newActionRecord(action, notifiedAt.Add(time.Duration(i)*interval))))
+	}
+	require.NoError(t, recorder.Close())
+}
+
+func TestRecorderPlayer(t *testing.T) {
+	monitor := newTestMonitor(t)
+	actions := newExportDatabase(t, monitor.abiDecoder, 3).actions
+	filename := filepath.Join(t.TempDir(), "record.jsonl")
+	writeTestRecord(t, filename, actions, time.Second)
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+
+	delays := make([]time.Duration, 0)
+	player := NewPlayer(f, 2)
+	player.sleep = func(delay time.Duration) <-chan time.Time {
+		delays = append(delays, delay)
+		return time.After(0)
+	}
+
+	for _, action := range actions {
+		record, err := player.Next(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, action, record.rows())
+	}
+	_, err = player.Next(context.Background())
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, delays)
+
+	// without delays
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	player = NewPlayer(f, 0)
+	player.sleep = func(delay time.Duration) <-chan time.Time {
+		t.Fatalf("unexpected delay %s", delay)
+		return nil
+	}
+	for range actions {
+		_, err = player.Next(context.Background())
+		require.NoError(t, err)
+	}
+
+	// the delay is interrupted by the context
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	player = NewPlayer(f, 1)
+	_, err = player.Next(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = player.Next(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestScraperReplay(t *testing.T) {
+	monitor := newTestMonitor(t)
+	actions := newExportDatabase(t, monitor.abiDecoder, 3).actions
+	filename := filepath.Join(t.TempDir(), "record.jsonl")
+	monitor.config.replay.speed = 0
+	writeTestRecord(t, filename, actions, time.Second)
+
+	session := newSession(monitor, nil)
+	subscribe := &ScraperSubscribeMessage{name: eventTopicName(0), session: session, response: make(chan *ScraperResponseMessage)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+
+	// subscribe before the replay starts
+	go monitor.scraper.run(ctx)
+	monitor.scraper.subscribe <- subscribe
+	<-subscribe.response
+	go monitor.scraper.replay(ctx, filename)
+
+	// the second action is not decoded
+	for _, offset := range []uint64{10, 12} {
+		select {
+		case event := <-session.queue:
+			assert.Equal(t, offset, event.Offset)
+			assert.Equal(t, actions[0].blockTime.Add(time.Duration(offset-10)*time.Second), event.BlockTime)
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not replayed", offset)
+		}
+	}
+
+	// the finished replay is alive but not ready
+	require.Eventually(t, monitor.scraper.replayed.IsSet, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return !monitor.scraper.listening.IsSet()
+	}, time.Second, 10*time.Millisecond)
+
+	code, _ := serveHealthTest(t, monitor, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, response := serveHealthTest(t, monitor, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, errReplayFinished.Error(), response.Checks[healthCheckListen].Error)
+}
+
+func TestScraperRecordMemory(t *testing.T) {
+	monitor, db := newTestMemoryMonitor(t)
+	monitor.config.record.file = filepath.Join(t.TempDir(), "record.jsonl")
+
+	session := newSession(monitor, nil)
+	subscribe := &ScraperSubscribeMessage{name: eventTopicName(0), session: session, response: make(chan *ScraperResponseMessage)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.games.run(ctx)
+	go monitor.scraper.run(ctx)
+	monitor.scraper.subscribe <- subscribe
+	<-subscribe.response
+	require.Eventually(t, monitor.scraper.listening.IsSet, time.Second, 10*time.Millisecond)
+
+	actData := encodeTestAction(t, monitor.abiDecoder, createStructData(t, 1, 2, "test_string"))
+	for _, offset := range []uint64{20, 21} {
+		db.AddAction(&DatabaseMemoryAction{Offset: offset, BlockNum: 1, ActAccount: "casino", ActName: defaultContractActionName, ActData: actData})
+	}
+	for range []uint64{20, 21} {
+		select {
+		case <-session.queue:
+		case <-time.After(time.Second):
+			t.Fatal("event not received")
+		}
+	}
+	monitor.scraper.stopListen()
+	require.Eventually(t, func() bool {
+		return !monitor.scraper.listening.IsSet()
+	}, time.Second, 10*time.Millisecond)
+
+	f, err := os.Open(monitor.config.record.file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	player := NewPlayer(f, 0)
+	for _, offset := range []uint64{20, 21} {
+		record, err := player.Next(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, offset, record.Offset)
+		assert.Equal(t, testBlockTime, record.BlockTime)
+		assert.Equal(t, actData, []byte(record.ActData))
+	}
+	_, err = player.Next(context.Background())
+	assert.Equal(t, io.EOF, err)
+}
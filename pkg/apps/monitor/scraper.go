@@ -3,9 +3,13 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/tevino/abool"
 	"go.uber.org/zap"
-	"strconv"
+	"io"
+	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,41 +31,87 @@ type ScraperBroadcastMessage struct {
 	response chan *ScraperResponseMessage
 }
 
+type ScraperTopicsQueryMessage struct {
+	response chan map[string][]*Session
+}
+
 type ScraperResponseMessage struct {
 	result interface{}
 	err    error
 }
 
 type Scraper struct {
+	monitor *Monitor
+	log     *zap.Logger
+
 	unsubscribeSession chan *Session
 	subscribe          chan *ScraperSubscribeMessage
 	unsubscribe        chan *ScraperUnsubscribeMessage
 	broadcast          chan *ScraperBroadcastMessage
+	topicsQuery        chan *ScraperTopicsQueryMessage
+
+	// stops the listen loop on shutdown before the monitor context
+	listenContext context.Context
+	stopListen    context.CancelFunc
 
 	topics map[string]map[*Session]bool
 	// last offset processed
 	offset uint64
+
+	// state of the listen loop for the health checks
+	listening *abool.AtomicBool
+	// set when the replay file is played to the end, the listen loop is not restarted
+	replayed *abool.AtomicBool
+	// unix nano time of the last notification or the listen start
+	lastNotify int64
+
+	// appends the fetched actions to the record file, set by openRecorder
+	recorder *Recorder
 }
 
-func newScraper() *Scraper {
-	return &Scraper{
+func newScraper(monitor *Monitor) *Scraper {
+	s := &Scraper{
+		monitor:            monitor,
+		log:                monitor.log.Scraper,
 		topics:             make(map[string]map[*Session]bool),
 		subscribe:          make(chan *ScraperSubscribeMessage),
 		unsubscribe:        make(chan *ScraperUnsubscribeMessage),
 		broadcast:          make(chan *ScraperBroadcastMessage),
+		topicsQuery:        make(chan *ScraperTopicsQueryMessage),
 		unsubscribeSession: make(chan *Session),
+		listening:          abool.New(),
+		replayed:           abool.New(),
+	}
+	s.listenContext, s.stopListen = context.WithCancel(monitor.ctx)
+	return s
+}
+
+func (s *Scraper) touchNotify(now time.Time) {
+	atomic.StoreInt64(&s.lastNotify, now.UnixNano())
+}
+
+func (s *Scraper) lastNotifyTime() time.Time {
+	if lastNotify := atomic.LoadInt64(&s.lastNotify); lastNotify != 0 {
+		return time.Unix(0, lastNotify)
 	}
+	return time.Time{}
 }
 
 func (s *Scraper) run(parentContext context.Context) {
-	log := scraperLog.Named("scraper")
+	log := s.log.Named("scraper")
 	defer func() {
 		log.Info("scraper stopped")
 	}()
 	log.Info("scraper started")
 
-	if pool != nil {
-		go scraper.listen(parentContext)
+	if s.monitor.config.replay.file != "" {
+		go s.replay(s.listenContext, s.monitor.config.replay.file)
+	} else if s.monitor.config.generator.rate > 0 {
+		go s.generate(s.listenContext)
+	} else if listener, ok := s.monitor.pool.(DatabaseListener); ok {
+		go s.listen(s.listenContext, listener)
+	} else if notifier, ok := s.monitor.pool.(DatabaseNotifier); ok {
+		go s.listenNotifier(s.listenContext, notifier)
 	}
 
 	for {
@@ -73,11 +123,15 @@ func (s *Scraper) run(parentContext context.Context) {
 		case session := <-s.unsubscribeSession:
 			log.Debug("unsubscribeSession", zap.String("session.ID", session.ID))
 			for name, topicSessions := range s.topics {
+				if _, ok := topicSessions[session]; !ok {
+					continue
+				}
 				delete(topicSessions, session)
 
 				if len(topicSessions) == 0 {
 					delete(s.topics, name)
 				}
+				s.observeTopic(name)
 			}
 
 		case message := <-s.subscribe:
@@ -93,6 +147,7 @@ func (s *Scraper) run(parentContext context.Context) {
 				topicClients[message.session] = true
 				s.topics[message.name] = topicClients
 			}
+			s.observeTopic(message.name)
 
 			if message.response != nil {
 				response := new(ScraperResponseMessage)
@@ -114,6 +169,7 @@ func (s *Scraper) run(parentContext context.Context) {
 				if len(topicClients) == 0 {
 					delete(s.topics, message.name)
 				}
+				s.observeTopic(message.name)
 				response.result = true
 			} else {
 				response.result = false
@@ -131,9 +187,15 @@ func (s *Scraper) run(parentContext context.Context) {
 			response := new(ScraperResponseMessage)
 
 			if topicClients, ok := s.topics[message.name]; ok {
+				start := time.Now()
 				for clientSession := range topicClients {
-					clientSession.queue <- message.event
+					select {
+					case <-parentContext.Done():
+						return
+					case clientSession.queue <- message.event:
+					}
 				}
+				s.monitor.metrics.BroadcastSeconds.Observe(time.Since(start).Seconds())
 				response.result = true
 			} else {
 				response.result = false
@@ -144,36 +206,98 @@ func (s *Scraper) run(parentContext context.Context) {
 				message.response <- response
 				close(message.response)
 			}
+
+		case message := <-s.topicsQuery:
+			topics := make(map[string][]*Session, len(s.topics))
+			for name, topicSessions := range s.topics {
+				sessions := make([]*Session, 0, len(topicSessions))
+				for session := range topicSessions {
+					sessions = append(sessions, session)
+				}
+				topics[name] = sessions
+			}
+			message.response <- topics
+			close(message.response)
 		}
 	}
 }
 
-func (s *Scraper) handleNotify(parentContext context.Context, conn *pgx.Conn, offset uint64) error {
-	scraperLog.Debug("handleNotify", zap.Uint64("offset", offset))
+// subscribers returns the sessions of every topic
+func (s *Scraper) subscribers(parentContext context.Context) (map[string][]*Session, error) {
+	message := &ScraperTopicsQueryMessage{make(chan map[string][]*Session, 1)}
 
-	s.offset = offset // save current offset
-	event, err := fetchEvent(parentContext, conn, offset)
+	select {
+	case <-parentContext.Done():
+		return nil, parentContext.Err()
+	case s.topicsQuery <- message:
+	}
 
+	return <-message.response, nil
+}
+
+func (s *Scraper) observeTopic(name string) {
+	if topicSessions, ok := s.topics[name]; ok {
+		s.monitor.metrics.TopicSubscribers.WithLabelValues(name).Set(float64(len(topicSessions)))
+	} else {
+		s.monitor.metrics.TopicSubscribers.DeleteLabelValues(name)
+	}
+}
+
+func (s *Scraper) handleNotify(parentContext context.Context, conn DatabaseConnect, offset uint64) error {
+	s.log.Debug("handleNotify", zap.Uint64("offset", offset))
+	notifyTime := time.Now()
+
+	s.offset = offset // save current offset
+	rows, err := s.monitor.fetchAction(parentContext, conn, offset)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			sessionLog.Debug("fetchEvent no rows", zap.Uint64("offset", offset))
+			s.log.Debug("fetchEvent no rows", zap.Uint64("offset", offset))
 			return nil
 		}
 		return fmt.Errorf("fetchEvent error: %s", err)
 	}
 
+	if s.recorder != nil {
+		if err := s.recorder.Record(newActionRecord(rows, notifyTime)); err != nil {
+			s.log.Error("record error", zap.Uint64("offset", offset), zap.Error(err))
+		}
+	}
+
+	event, err := s.monitor.decodeRows(s.monitor.config.getSources(), rows)
+	if err != nil {
+		return fmt.Errorf("fetchEvent error: %s", err)
+	}
+
+	s.publish(parentContext, event, notifyTime)
+	return nil
+}
+
+// publish adds the event to the game tracker and broadcasts it to the topic
+func (s *Scraper) publish(parentContext context.Context, event *Event, notifyTime time.Time) {
+	event.NotifiedAt = &notifyTime
+	s.monitor.games.add(parentContext, event)
+
+	message := &ScraperBroadcastMessage{eventTopic(event), event, make(chan *ScraperResponseMessage, 1)}
+
 	select {
 	case <-parentContext.Done():
-	case s.broadcast <- &ScraperBroadcastMessage{fmt.Sprintf("event_%d", event.EventType), event, nil}:
+		return
+	case s.broadcast <- message:
+	}
+
+	select {
+	case <-parentContext.Done():
+	case <-message.response:
+		s.monitor.metrics.NotifyToBroadcastSeconds.Observe(time.Since(notifyTime).Seconds())
 	}
-	return nil
 }
 
-func (s *Scraper) listen(parentContext context.Context) {
-	log := scraperLog.Named("scraper listen")
-	conn, err := pool.Acquire(parentContext)
+func (s *Scraper) listen(parentContext context.Context, listener DatabaseListener) {
+	log := s.log.Named("scraper listen")
+	conn, err := listener.Acquire(parentContext)
 	if err != nil {
 		log.Error("pool acquire connection error", zap.Error(err))
+		return
 	}
 
 	log.Info("listen notify start")
@@ -183,42 +307,253 @@ func (s *Scraper) listen(parentContext context.Context) {
 		log.Info("listen notify stop")
 	}()
 
-	_, err = conn.Exec(parentContext, "listen new_action_trace")
+	channel := s.monitor.config.db.channel
+	if s.monitor.config.db.notifyFilter {
+		err = syncNotifyFilter(parentContext, conn.Conn(), channel, sourceFilters(s.monitor.config.getSources()))
+		if err != nil {
+			log.Error("notify filter error", zap.String("channel", channel), zap.Error(err))
+			return
+		}
+		defer func() {
+			// the parent context is done on stop
+			ctx, cancel := context.WithTimeout(context.Background(), notifyFilterCleanupTimeout)
+			defer cancel()
+			if err := syncNotifyFilter(ctx, conn.Conn(), channel, nil); err != nil {
+				log.Error("notify filter cleanup error", zap.String("channel", channel), zap.Error(err))
+			}
+		}()
+	}
+
+	closeRecorder, err := s.openRecorder(log)
+	if err != nil {
+		return
+	}
+	defer closeRecorder()
+
+	_, err = conn.Exec(parentContext, "listen "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		log.Error("error listening", zap.String("channel", channel), zap.Error(err))
+		return
+	}
+
+	s.receive(parentContext, log, conn.Conn(), func(ctx context.Context) (*pgconn.Notification, error) {
+		contextWithTimeout, cancelWaitForNotification := context.WithTimeout(ctx, time.Second)
+		defer cancelWaitForNotification()
+		return conn.Conn().WaitForNotification(contextWithTimeout)
+	})
+}
+
+// listenNotifier receives the notifications of the pool implementing DatabaseNotifier, eg DatabaseMemory
+func (s *Scraper) listenNotifier(parentContext context.Context, notifier DatabaseNotifier) {
+	log := s.log.Named("scraper listen")
+	log.Info("listen notify start")
+	defer log.Info("listen notify stop")
+
+	channel := s.monitor.config.db.channel
+	if s.monitor.config.db.notifyFilter {
+		notifier.SetNotifyFilter(channel, sourceFilters(s.monitor.config.getSources()))
+		defer notifier.SetNotifyFilter(channel, nil)
+	}
+
+	closeRecorder, err := s.openRecorder(log)
 	if err != nil {
-		log.Error("error listening new_action_trace", zap.Error(err))
 		return
 	}
+	defer closeRecorder()
+
+	notifications := notifier.Listen(parentContext, channel)
+	s.receive(parentContext, log, s.monitor.pool, func(ctx context.Context) (*pgconn.Notification, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case notification := <-notifications:
+			return notification, nil
+		}
+	})
+}
+
+// openRecorder opens the record file if it is configured, the returned function closes it
+func (s *Scraper) openRecorder(log *zap.Logger) (func(), error) {
+	file := s.monitor.config.record.file
+	if file == "" {
+		return func() {}, nil
+	}
+
+	recorder, err := NewRecorder(file)
+	if err != nil {
+		log.Error("record file error", zap.String("file", file), zap.Error(err))
+		return nil, err
+	}
+	s.recorder = recorder
+	log.Info("recording", zap.String("file", file))
+
+	return func() {
+		recorder.Close()
+	}, nil
+}
+
+// receive handles the notifications returned by wait until the context is done or an action fails to be handled,
+// the actions are fetched with conn
+func (s *Scraper) receive(parentContext context.Context, log *zap.Logger, conn DatabaseConnect, wait func(context.Context) (*pgconn.Notification, error)) {
+	s.touchNotify(time.Now())
+	s.listening.Set()
+	defer s.listening.UnSet()
 
+	sources := s.monitor.config.getSources()
 	for {
 		select {
 		case <-parentContext.Done():
 			log.Debug("listen parent context done")
 			return
 		default:
-			contextWithTimeout, cancelWaitForNotification := context.WithTimeout(parentContext, time.Second)
-			notification, err := conn.Conn().WaitForNotification(contextWithTimeout)
-			if err == nil {
-				log.Debug("notify",
-					zap.Uint32("PID", notification.PID),
-					zap.String("channel", notification.Channel),
-					zap.String("payload", notification.Payload),
-				)
-
-				offset, err := strconv.ParseInt(notification.Payload, 10, 64)
-				if err != nil {
-					log.Error("parseInt error", zap.Error(err))
-					cancelWaitForNotification()
-					return
-				}
+		}
 
-				err = s.handleNotify(parentContext, conn.Conn(), uint64(offset))
-				if err != nil {
-					log.Error("handleNotify error", zap.Error(err))
-					cancelWaitForNotification()
-					return
-				}
+		notification, err := wait(parentContext)
+		if err != nil {
+			continue
+		}
+
+		s.touchNotify(time.Now())
+		log.Debug("notify",
+			zap.Uint32("PID", notification.PID),
+			zap.String("channel", notification.Channel),
+			zap.String("payload", notification.Payload),
+		)
+
+		action, err := parseNotification(notification.Payload)
+		if err != nil {
+			log.Error("notification payload error", zap.Error(err))
+			s.monitor.metrics.NotificationsTotal.WithLabelValues(notificationInvalid).Inc()
+			continue
+		}
+
+		if !action.match(sources) {
+			s.monitor.metrics.NotificationsTotal.WithLabelValues(notificationDiscarded).Inc()
+			continue
+		}
+
+		s.monitor.metrics.NotificationsTotal.WithLabelValues(notificationFetched).Inc()
+		if err := s.handleNotify(parentContext, conn, action.Offset); err != nil {
+			log.Error("handleNotify error", zap.Error(err))
+			return
+		}
+	}
+}
+
+// replay publishes the actions of the record file instead of the notifications,
+// the offsets of the record are the offsets of the events
+func (s *Scraper) replay(parentContext context.Context, filename string) {
+	log := s.log.Named("scraper replay")
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Error("replay file error", zap.String("file", filename), zap.Error(err))
+		return
+	}
+	defer f.Close()
+
+	log.Info("replay start", zap.String("file", filename), zap.Float64("speed", s.monitor.config.replay.speed))
+
+	s.touchNotify(time.Now())
+	s.listening.Set()
+	defer s.listening.UnSet()
+
+	sources := s.monitor.config.getSources()
+	player := NewPlayer(f, s.monitor.config.replay.speed)
+	for count := 0; ; count++ {
+		record, err := player.Next(parentContext)
+		switch {
+		case err == io.EOF:
+			log.Info("replay finished", zap.Int("actions", count))
+			s.replayed.Set()
+			return
+		case parentContext.Err() != nil:
+			log.Debug("replay parent context done")
+			return
+		case err != nil:
+			log.Error("replay record error", zap.Int("line", count+1), zap.Error(err))
+			return
+		}
+
+		notifyTime := time.Now()
+		s.touchNotify(notifyTime)
+		s.offset = record.Offset
+
+		event, err := s.monitor.decodeRows(sources, record.rows())
+		if err != nil {
+			log.Error("replay decode error", zap.Uint64("offset", record.Offset), zap.Error(err))
+			continue
+		}
+		s.publish(parentContext, event, notifyTime)
+	}
+}
+
+// generate publishes the synthetic events instead of the notifications
+func (s *Scraper) generate(parentContext context.Context) {
+	log := s.log.Named("scraper generator")
+	generator, err := NewGenerator(s.monitor.config, s.monitor.abiDecoder)
+	if err != nil {
+		log.Error("generator error", zap.Error(err))
+		return
+	}
+
+	config := s.monitor.config.generator
+	log.Info("generator start",
+		zap.Float64("rate", config.rate),
+		zap.Int("casinos", config.casinos),
+		zap.Int("games", config.games),
+		zap.String("distribution", config.distribution),
+	)
+	defer log.Info("generator stop")
+
+	s.touchNotify(time.Now())
+	s.listening.Set()
+	defer s.listening.UnSet()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / config.rate))
+	defer ticker.Stop()
+
+	// the memory database keeps the generated actions for the subscriptions from an offset until they expire
+	db, store := s.monitor.pool.(*DatabaseMemory)
+	expires, err := parseInterval(s.monitor.config.eventExpires)
+	if err != nil {
+		log.Error("generator event expires error", zap.Error(err))
+		return
+	}
+	cleanup := time.NewTicker(generatorCleanupPeriod)
+	defer cleanup.Stop()
+	var blockNum uint32
+
+	sources := s.monitor.config.getSources()
+	for {
+		select {
+		case <-parentContext.Done():
+			return
+		case now := <-cleanup.C:
+			if store {
+				db.deleteBefore(now.Add(-expires))
+			}
+		case notifyTime := <-ticker.C:
+			s.touchNotify(notifyTime)
+
+			rows, err := generator.Next(notifyTime)
+			if err != nil {
+				log.Error("generator event error", zap.Error(err))
+				return
+			}
+			s.offset = rows.offset
+
+			if store {
+				blockNum++
+				db.AddBlock(blockNum, rows.blockTime)
+				db.AddAction(&DatabaseMemoryAction{Offset: rows.offset, BlockNum: blockNum, ActAccount: rows.actAccount, ActName: rows.actName, ActData: rows.actData})
+			}
+
+			event, err := s.monitor.decodeRows(sources, rows)
+			if err != nil {
+				log.Error("generator decode error", zap.Uint64("offset", rows.offset), zap.Error(err))
+				continue
 			}
-			cancelWaitForNotification()
+			s.publish(parentContext, event, notifyTime)
 		}
 	}
 }
@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -10,8 +11,9 @@ func TestScraperSubscribe(t *testing.T) {
 	// session, teardownTestCase := setupSessionTestCase(t)
 	// defer teardownTestCase(t)
 
-	scraper := newScraper()
-	session := newSession(scraper, nil)
+	monitor := newTestMonitor(t)
+	scraper := monitor.scraper
+	session := newSession(monitor, nil)
 	message := &ScraperSubscribeMessage{
 		name:     "test",
 		session:  session,
@@ -21,29 +23,30 @@ func TestScraperSubscribe(t *testing.T) {
 	parentContext, cancel := context.WithCancel(context.Background())
 	go scraper.run(parentContext)
 
-	session.scraper.subscribe <- message
+	scraper.subscribe <- message
 	response := <-message.response
 
 	assert.Equal(t, true, response.result)
 
 	cancel()
-	// assert.Equal(t, 1, len(session.scraper.topics))
+	// assert.Equal(t, 1, len(scraper.topics))
 
-	// _, ok := session.scraper.topics[message.name]
+	// _, ok := scraper.topics[message.name]
 	// assert.Equal(t, true, ok)
 }
 
 func TestScraperUnsubscribe(t *testing.T) {
 	const topicName = "test"
 
-	scraper := newScraper()
-	session := newSession(scraper, nil)
+	monitor := newTestMonitor(t)
+	scraper := monitor.scraper
+	session := newSession(monitor, nil)
 	subscribeMessage := &ScraperSubscribeMessage{name: topicName, session: session, response: nil}
 
 	parentContext, cancel := context.WithCancel(context.Background())
 	go scraper.run(parentContext)
 
-	session.scraper.subscribe <- subscribeMessage
+	scraper.subscribe <- subscribeMessage
 
 	unsubscribeMessage := &ScraperUnsubscribeMessage{
 		name:     "123",
@@ -51,7 +54,7 @@ func TestScraperUnsubscribe(t *testing.T) {
 		response: make(chan *ScraperResponseMessage),
 	}
 
-	session.scraper.unsubscribe <- unsubscribeMessage
+	scraper.unsubscribe <- unsubscribeMessage
 	response := <-unsubscribeMessage.response
 
 	assert.Equal(t, false, response.result)
@@ -59,15 +62,37 @@ func TestScraperUnsubscribe(t *testing.T) {
 	//unsubscribeMessage.name = topicName
 	//unsubscribeMessage.response = make(chan *ScraperResponseMessage)
 	//
-	//session.scraper.unsubscribe <- unsubscribeMessage
+	//scraper.unsubscribe <- unsubscribeMessage
 	//res := <-unsubscribeMessage.response
 	//
 	//assert.Equal(t, true, res.result)
 
 	cancel()
-	// assert.Equal(t, 0, len(session.scraper.topics))
+	// assert.Equal(t, 0, len(scraper.topics))
 }
 
 func TestBroadcastMessage(t *testing.T) {
 	t.Skip("need mock websocket connection")
 }
+
+func TestScraperTopicSubscribersMetric(t *testing.T) {
+	const topicName = "test_metric"
+
+	monitor := newTestMonitor(t)
+	scraper := monitor.scraper
+	session := newSession(monitor, nil)
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scraper.run(parentContext)
+
+	response := make(chan *ScraperResponseMessage)
+	scraper.subscribe <- &ScraperSubscribeMessage{name: topicName, session: session, response: response}
+	<-response
+	assert.Equal(t, float64(1), testutil.ToFloat64(monitor.metrics.TopicSubscribers.WithLabelValues(topicName)))
+
+	response = make(chan *ScraperResponseMessage)
+	scraper.unsubscribe <- &ScraperUnsubscribeMessage{name: topicName, session: session, response: response}
+	<-response
+	assert.Equal(t, float64(0), testutil.ToFloat64(monitor.metrics.TopicSubscribers.WithLabelValues(topicName)))
+}
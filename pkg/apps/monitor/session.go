@@ -40,9 +40,12 @@ func (q *Queue) add(event *Event) {
 }
 
 type Session struct {
-	ID string
+	ID          string
+	remoteAddr  string
+	connectedAt time.Time
 
-	scraper *Scraper
+	monitor *Monitor
+	log     *zap.Logger
 
 	// The websocket connection.
 	conn *websocket.Conn
@@ -54,15 +57,28 @@ type Session struct {
 
 	sync.Mutex
 	offset uint64
+	// sha256 prefix and the user of the last token accepted on subscribe, the token is not kept
+	tokenHash string
+	user      *User
+
+	closeOnce sync.Once
 }
 
-func newSession(scraper *Scraper, conn *websocket.Conn) *Session {
+func newSession(monitor *Monitor, conn *websocket.Conn) *Session {
 	ID := cuid.New()
-	sessionLog.Debug("new session", zap.String("ID", ID))
+	monitor.log.Session.Debug("new session", zap.String("ID", ID))
+
+	remoteAddr := ""
+	if conn != nil {
+		remoteAddr = conn.RemoteAddr().String()
+	}
 
 	return &Session{
 		ID:            ID,
-		scraper:       scraper,
+		remoteAddr:    remoteAddr,
+		connectedAt:   time.Now(),
+		monitor:       monitor,
+		log:           monitor.log.Session,
 		conn:          conn,
 		send:          make(chan *dataToSocket, 512),
 		queue:         make(chan *Event),
@@ -82,41 +98,99 @@ func (s *Session) Offset() uint64 {
 	return s.offset
 }
 
+func (s *Session) setToken(token string, user *User) {
+	s.Lock()
+	defer s.Unlock()
+	s.tokenHash = tokenHashPrefix(token)
+	s.user = user
+}
+
+// Token returns the sha256 prefix of the token and its user, nil without the token check
+func (s *Session) Token() (string, *User) {
+	s.Lock()
+	defer s.Unlock()
+	return s.tokenHash, s.user
+}
+
+// Reasons of the websocket close metric
+const (
+	closeReasonClient      = "client_close"
+	closeReasonReadError   = "read_error"
+	closeReasonProcess     = "process_error"
+	closeReasonWriteError  = "write_error"
+	closeReasonPingError   = "ping_error"
+	closeReasonQueueClosed = "queue_closed"
+	closeReasonShutdown    = "shutdown"
+	closeReasonAdmin       = "admin_disconnect"
+)
+
+// observeClose counts the first reason of the session close only
+func (s *Session) observeClose(reason string) {
+	s.closeOnce.Do(func() {
+		s.monitor.metrics.WebsocketCloseTotal.WithLabelValues(reason).Inc()
+	})
+}
+
+// disconnect sends the close frame and closes the connection, the read pump unregisters the session
+func (s *Session) disconnect(reason string, code int, text string) {
+	s.observeClose(reason)
+	if err := s.sendCloseControl(code, text); err != nil {
+		s.log.Debug("sendCloseControl error", zap.Error(err), zap.String("session.id", s.ID))
+	}
+	_ = s.conn.Close()
+}
+
+func (s *Session) observeSendBuffer() {
+	s.monitor.metrics.SessionSendBufferDepth.Observe(float64(len(s.send)))
+}
+
 func (s *Session) readPump(parentContext context.Context) {
-	log := sessionLog.Named("readPump")
+	log := s.log.Named("readPump")
 
 	readPumpContext, cancel := context.WithCancel(parentContext)
 	defer func() {
 		cancel()
-		sessionManager.unregister <- s
+		select {
+		case <-s.monitor.ctx.Done():
+		case s.monitor.sessionManager.unregister <- s:
+		}
 		_ = s.conn.Close()
 		log.Debug("pump close", zap.String("session.id", s.ID))
 	}()
 
 	log.Debug("pump start", zap.String("session.id", s.ID))
 
-	s.conn.SetReadLimit(config.session.messageSizeLimit)
-	if err := s.conn.SetReadDeadline(time.Now().Add(config.session.pongWait)); err != nil {
+	sessionConfig := &s.monitor.config.session
+	s.conn.SetReadLimit(sessionConfig.messageSizeLimit)
+	if err := s.conn.SetReadDeadline(time.Now().Add(sessionConfig.pongWait)); err != nil {
 		return
 	}
-	s.conn.SetPongHandler(func(string) error { return s.conn.SetReadDeadline(time.Now().Add(config.session.pongWait)) })
+	s.conn.SetPongHandler(func(string) error { return s.conn.SetReadDeadline(time.Now().Add(sessionConfig.pongWait)) })
 
 	for {
 		select {
 		case <-parentContext.Done():
-			sessionLog.Debug("readPump parent context close, close connection")
+			s.log.Debug("readPump parent context close, close connection")
+			s.observeClose(closeReasonShutdown)
 			return
 		default:
 			_, message, err := s.conn.ReadMessage()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					sessionLog.Error("readPump", zap.String("session.id", s.ID), zap.Error(err))
+					s.log.Error("readPump", zap.String("session.id", s.ID), zap.Error(err))
+				}
+
+				if _, ok := err.(*websocket.CloseError); ok {
+					s.observeClose(closeReasonClient)
+				} else {
+					s.observeClose(closeReasonReadError)
 				}
 				return
 			}
 
 			if err := s.process(readPumpContext, message); err != nil {
-				sessionLog.Error("process error", zap.String("session.id", s.ID), zap.Error(err))
+				s.log.Error("process error", zap.String("session.id", s.ID), zap.Error(err))
+				s.observeClose(closeReasonProcess)
 				return
 			}
 		}
@@ -124,7 +198,7 @@ func (s *Session) readPump(parentContext context.Context) {
 }
 
 func (s *Session) queuePump(parentContext context.Context, done chan struct{}) {
-	log := sessionLog.Named("queuePump")
+	log := s.log.Named("queuePump")
 
 	defer func() {
 		done <- struct{}{}
@@ -152,7 +226,7 @@ func (s *Session) queuePump(parentContext context.Context, done chan struct{}) {
 				events[0] = event
 
 				// this blocked
-				if err := s.sendChunked(parentContext, events); err != nil {
+				if err := s.sendChunked(parentContext, events, deliveryLive); err != nil {
 					log.Debug("sendChunked error", zap.Error(err), zap.String("session.id", s.ID))
 					return
 				}
@@ -165,8 +239,8 @@ func (s *Session) queuePump(parentContext context.Context, done chan struct{}) {
 }
 
 func (s *Session) writePump(parentContext context.Context) {
-	log := sessionLog.Named("writePump")
-	ticker := time.NewTicker(config.session.pingPeriod)
+	log := s.log.Named("writePump")
+	ticker := time.NewTicker(s.monitor.config.session.pingPeriod)
 
 	ctx, cancel := context.WithCancel(parentContext)
 	queuePumpClosed := make(chan struct{})
@@ -185,10 +259,12 @@ func (s *Session) writePump(parentContext context.Context) {
 		select {
 		case <-parentContext.Done():
 			log.Debug("parent context close", zap.String("session.id", s.ID))
+			s.observeClose(closeReasonShutdown)
 			return
 
 		case <-queuePumpClosed:
-			if err := sendCloseMessage(s.conn); err != nil {
+			s.observeClose(closeReasonQueueClosed)
+			if err := s.sendCloseMessage(); err != nil {
 				log.Error("sendCloseMessage error", zap.Error(err), zap.String("session.id", s.ID))
 			}
 			return
@@ -196,21 +272,24 @@ func (s *Session) writePump(parentContext context.Context) {
 		case data, ok := <-s.send:
 			if !ok {
 				log.Debug("send chan close", zap.String("session.id", s.ID))
-				if err := sendCloseMessage(s.conn); err != nil {
+				s.observeClose(closeReasonQueueClosed)
+				if err := s.sendCloseMessage(); err != nil {
 					log.Error("sendCloseMessage error", zap.Error(err), zap.String("session.id", s.ID))
 				}
 				return
 			}
 
-			if err := sendMessage(s.conn, data); err != nil {
+			if err := s.sendMessage(data); err != nil {
 				log.Error("sendMessage error", zap.Error(err), zap.String("session.id", s.ID))
+				s.observeClose(closeReasonWriteError)
 				return
 			}
 		case <-ticker.C:
 			log.Debug("ping", zap.String("session.id", s.ID))
 
-			if err := sendPingMessage(s.conn); err != nil {
+			if err := s.sendPingMessage(); err != nil {
 				log.Error("sendPingMessage error", zap.Error(err), zap.String("session.id", s.ID))
+				s.observeClose(closeReasonPingError)
 				return
 			}
 		}
@@ -258,16 +337,17 @@ func (s *Session) process(parentContext context.Context, message []byte) error {
 	defer func() {
 		raw, err := json.Marshal(response)
 		if err != nil {
-			sessionLog.Error("response marshal", zap.Error(err))
+			s.log.Error("response marshal", zap.Error(err))
 			return
 		}
 
 		data := newSendData(raw)
+		s.observeSendBuffer()
 		s.send <- data
 		<-data.done // TODO: <- block
 
 		if data.err != nil {
-			sessionLog.Error("send error", zap.Error(data.err))
+			s.log.Error("send error", zap.Error(data.err))
 			return
 		}
 
@@ -284,7 +364,7 @@ func (s *Session) process(parentContext context.Context, message []byte) error {
 	if err != nil {
 		response.setError(err)
 
-		sessionLog.Debug("response error",
+		s.log.Debug("response error",
 			zap.Stringp("ID", response.ID),
 			zap.Int("code", response.Error.Code),
 			zap.String("message", response.Error.Message),
@@ -298,7 +378,7 @@ func (s *Session) process(parentContext context.Context, message []byte) error {
 		return err
 	}
 
-	sessionLog.Debug("response",
+	s.log.Debug("response",
 		zap.Stringp("ID", response.ID),
 		zap.String("result", string(response.Result)),
 	)
@@ -2,80 +2,115 @@ package monitor
 
 import (
 	"context"
-	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor/metrics"
-	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	"net/http"
 )
 
-var upgrader websocket.Upgrader
+type SessionManagerQueryMessage struct {
+	response chan []*Session
+}
 
 type SessionManager struct {
+	monitor *Monitor
+	log     *zap.Logger
+
 	sessions   map[*Session]bool
 	register   chan *Session
 	unregister chan *Session
+	query      chan *SessionManagerQueryMessage
 }
 
-func newSessionManager() *SessionManager {
-	upgrader = websocket.Upgrader{
-		ReadBufferSize:  config.upgrader.readBufferSize,
-		WriteBufferSize: config.upgrader.writeBufferSize,
-		CheckOrigin:     func(r *http.Request) bool { return true },
-	}
-
+func newSessionManager(monitor *Monitor) *SessionManager {
 	return &SessionManager{
+		monitor:    monitor,
+		log:        monitor.log.Session,
 		sessions:   make(map[*Session]bool),
 		register:   make(chan *Session),
 		unregister: make(chan *Session),
+		query:      make(chan *SessionManagerQueryMessage),
 	}
 }
 
 func (s *SessionManager) run(parentContext context.Context) {
 	defer func() {
+		// the scraper and the session pumps stop by the same context
 		for session := range s.sessions {
-			session.scraper.unsubscribeSession <- session
-
 			delete(s.sessions, session)
-			close(session.queue)
-			metrics.UsersOnline.Dec()
+			s.monitor.metrics.UsersOnline.Dec()
 		}
-		sessionLog.Info("session manager stopped")
+		s.log.Info("session manager stopped")
 	}()
 
-	sessionLog.Info("session manager started")
+	s.log.Info("session manager started")
 
 	for {
 		select {
 		case <-parentContext.Done():
-			sessionLog.Debug("session manager parent context done")
+			s.log.Debug("session manager parent context done")
 			return
 		case session := <-s.register:
 			s.sessions[session] = true
-			metrics.UsersOnline.Inc()
+			s.monitor.metrics.UsersOnline.Inc()
 		case session := <-s.unregister:
 			if _, ok := s.sessions[session]; ok {
-				session.scraper.unsubscribeSession <- session
+				select {
+				case <-parentContext.Done():
+					return
+				case s.monitor.scraper.unsubscribeSession <- session:
+				}
 
 				delete(s.sessions, session)
 				close(session.queue)
-				metrics.UsersOnline.Dec()
+				s.monitor.metrics.UsersOnline.Dec()
+			}
+		case message := <-s.query:
+			sessions := make([]*Session, 0, len(s.sessions))
+			for session := range s.sessions {
+				sessions = append(sessions, session)
 			}
+			message.response <- sessions
+			close(message.response)
 		}
 	}
 }
 
-func serveWs(parentContext context.Context, scraper *Scraper, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// list returns the registered sessions
+func (s *SessionManager) list(parentContext context.Context) ([]*Session, error) {
+	message := &SessionManagerQueryMessage{make(chan []*Session, 1)}
+
+	select {
+	case <-parentContext.Done():
+		return nil, parentContext.Err()
+	case s.query <- message:
+	}
+
+	return <-message.response, nil
+}
+
+func (m *Monitor) serveWs(w http.ResponseWriter, r *http.Request) {
+	if m.shutdown.IsSet() {
+		http.Error(w, errShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := m.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		sessionLog.Error("upgrade", zap.Error(err))
+		m.log.Session.Error("upgrade", zap.Error(err))
 		return
 	}
 
-	session := newSession(scraper, conn)
-	sessionManager.register <- session
+	session := newSession(m, conn)
+	select {
+	case <-m.ctx.Done():
+		// the session manager is stopped, nobody takes the session
+		m.log.Session.Debug("monitor stopped, close the connection", zap.String("session.id", session.ID))
+		conn.Close()
+		return
+	case m.sessionManager.register <- session:
+	}
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
-	go session.writePump(parentContext)
-	go session.readPump(parentContext)
+	go session.writePump(m.ctx)
+	go session.readPump(m.ctx)
 }
@@ -3,6 +3,8 @@ package monitor
 import (
 	"context"
 	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"math/rand"
@@ -13,30 +15,23 @@ import (
 )
 
 func setupSessionTestCase(t *testing.T) (*Session, func(t *testing.T)) {
-	var err error
-
-	config = newConfig()
-	config.skipTokenCheck = true
-
-	scraper = newScraper()
-	sessionManager = newSessionManager()
-
-	abiDecoder, err = newAbiDecoder(&config.abi)
-	require.NoError(t, err)
+	monitor := newTestMonitor(t)
 
 	contextSessionTestCase, cancel := context.WithCancel(context.Background())
-	go sessionManager.run(contextSessionTestCase)
+	go monitor.sessionManager.run(contextSessionTestCase)
 	t.Log("session manager running")
-	go scraper.run(contextSessionTestCase)
+	go monitor.scraper.run(contextSessionTestCase)
 	t.Log("scraper running")
+	go monitor.games.run(contextSessionTestCase)
+	t.Log("game tracker running")
 
-	session := newSession(scraper, nil)
-	sessionManager.register <- session
+	session := newSession(monitor, nil)
+	monitor.sessionManager.register <- session
 
 	t.Log("session register")
 
 	return session, func(t *testing.T) {
-		sessionManager.unregister <- session
+		monitor.sessionManager.unregister <- session
 		t.Log("session unregister")
 		cancel()
 		t.Log("scraper stopped")
@@ -124,7 +119,9 @@ func TestSessionProcess(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			processed := make(chan struct{})
 			go func(t *testing.T) {
+				defer close(processed)
 				if err := session.process(ctx, []byte(tc.request)); err != nil {
 					t.Log(err)
 				}
@@ -134,6 +131,7 @@ func TestSessionProcess(t *testing.T) {
 
 			result.done <- struct{}{}
 			close(result.done)
+			<-processed
 
 			if string(result.data) != tc.expected {
 				t.Fatalf("expected %s, but got %s", tc.expected, string(result.data))
@@ -182,10 +180,9 @@ func newRandomEvent() *Event {
 }
 
 func TestSessionSendQueueMessages(t *testing.T) {
-	config = newConfig()
 	const numEvents = 10
 
-	session := newSession(nil, nil)
+	session := newSession(newTestMonitor(t), nil)
 	session.setOffset(0)
 
 	for i := 0; i < numEvents; i++ {
@@ -226,10 +223,50 @@ func TestSessionSendQueueMessages(t *testing.T) {
 	assert.Equal(t, 0, len(session.queueMessages.events))
 }
 
+func TestSessionSendQueueMessagesAlert(t *testing.T) {
+	session := newSession(newTestMonitor(t), nil)
+	session.setOffset(5)
+
+	session.queueMessages.add(&Event{Offset: 5})
+	session.queueMessages.add(&Event{EventType: EventAlertStuckGame})
+	session.queueMessages.add(&Event{Offset: 6})
+
+	messages := make(chan *EventMessage, 1)
+	go func() {
+		data := <-session.send
+		responseMessage := new(ResponseMessage)
+		eventMessage := new(EventMessage)
+		if err := json.Unmarshal(data.data, responseMessage); err == nil {
+			_ = json.Unmarshal(responseMessage.Result, eventMessage)
+		}
+		messages <- eventMessage
+		data.done <- struct{}{}
+	}()
+
+	require.NoError(t, session.sendQueueMessages(context.Background()))
+
+	// the alert is sent though its offset is less than the session offset
+	eventMessage := <-messages
+	assert.Equal(t, uint64(6), eventMessage.Offset)
+	require.Equal(t, 1, len(eventMessage.Events))
+	assert.Equal(t, uint64(6), eventMessage.Events[0].Offset)
+	require.Equal(t, 1, len(eventMessage.Alerts))
+	assert.Equal(t, EventAlertStuckGame, eventMessage.Alerts[0].EventType)
+	assert.Equal(t, uint64(6), session.Offset())
+	assert.Equal(t, 0, len(session.queueMessages.events))
+}
+
+func TestNewEventMessageAlerts(t *testing.T) {
+	message := newEventMessage([]*Event{{EventType: EventAlertStuckGame}}, 7)
+	assert.Equal(t, uint64(7), message.Offset)
+	assert.Equal(t, 0, len(message.Events))
+	assert.Equal(t, 1, len(message.Alerts))
+}
+
 func TestSessionSendMessages(t *testing.T) {
 	const numEvents = 10
 
-	session := newSession(nil, nil)
+	session := newSession(newTestMonitor(t), nil)
 	session.setOffset(0)
 
 	events := make([]*Event, 0)
@@ -260,7 +297,7 @@ func TestSessionSendMessages(t *testing.T) {
 		}
 	}()
 
-	eventMessage, err := newEventMessage(events)
+	eventMessage, err := marshalEventMessage(newEventMessage(events, 0))
 	require.NoError(t, err)
 
 	data := newSendData(eventMessage)
@@ -268,3 +305,22 @@ func TestSessionSendMessages(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestObserveDelivery(t *testing.T) {
+	observer := newTestMonitor(t).metrics
+	metric := new(dto.Metric)
+	require.NoError(t, observer.EventDeliverySeconds.WithLabelValues(deliveryReplay).(prometheus.Histogram).Write(metric))
+	count := metric.GetHistogram().GetSampleCount()
+
+	events := []*Event{
+		{BlockTime: time.Now().Add(-time.Second)},
+		{},
+		{EventType: EventAlertStuckGame, BlockTime: time.Now().Add(-time.Hour)},
+	}
+	observeDelivery(observer, events, deliveryReplay)
+
+	require.NoError(t, observer.EventDeliverySeconds.WithLabelValues(deliveryReplay).(prometheus.Histogram).Write(metric))
+	assert.Equal(t, count+1, metric.GetHistogram().GetSampleCount())
+	assert.True(t, metric.GetHistogram().GetSampleSum() >= 1)
+	assert.True(t, metric.GetHistogram().GetSampleSum() < 60)
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DaoCasino/platform-action-monitor/pkg/protocol"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"sync"
+)
+
+const shutdownCloseReason = "going away, reconnect"
+
+var errShuttingDown = errors.New("monitor is shutting down")
+
+// CloseMessage is the text of the close frame sent to the sessions on shutdown
+type CloseMessage = protocol.CloseMessage
+
+// inflight counts the running deliveries
+type inflight struct {
+	sync.Mutex
+	count int
+	// closed when count is zero
+	idle chan struct{}
+}
+
+func (i *inflight) add() {
+	i.Lock()
+	defer i.Unlock()
+	if i.count == 0 {
+		i.idle = make(chan struct{})
+	}
+	i.count++
+}
+
+func (i *inflight) done() {
+	i.Lock()
+	defer i.Unlock()
+	i.count--
+	if i.count == 0 {
+		close(i.idle)
+	}
+}
+
+func (i *inflight) wait(parentContext context.Context) error {
+	i.Lock()
+	if i.count == 0 {
+		i.Unlock()
+		return nil
+	}
+	idle := i.idle
+	i.Unlock()
+
+	select {
+	case <-parentContext.Done():
+		return parentContext.Err()
+	case <-idle:
+		return nil
+	}
+}
+
+// Shutdown stops accepting connections and listening for notifications, waits for the in-flight
+// deliveries until parentContext is done, sends every session the going away close frame
+// with the last delivered offset and closes the monitor.
+// Returns the parentContext error if the deliveries are not finished in time.
+func (m *Monitor) Shutdown(parentContext context.Context) error {
+	m.log.Main.Info("monitor shutdown")
+	m.shutdown.Set()
+	m.scraper.stopListen()
+
+	err := m.deliveries.wait(parentContext)
+	if err != nil {
+		m.log.Main.Warn("in-flight deliveries not finished", zap.Error(err))
+	}
+
+	// parentContext may be already done, the session manager is running until Close
+	listContext, cancel := context.WithTimeout(m.ctx, m.config.session.writeWait)
+	sessions, listErr := m.sessionManager.list(listContext)
+	cancel()
+	if listErr != nil {
+		m.log.Main.Error("shutdown sessions list error", zap.Error(listErr))
+	}
+
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *Session) {
+			defer wg.Done()
+			session.goingAway()
+		}(session)
+	}
+	wg.Wait()
+
+	m.Close()
+	m.log.Sync()
+	return err
+}
+
+func (s *Session) goingAway() {
+	text, err := json.Marshal(&CloseMessage{Reason: shutdownCloseReason, Offset: s.Offset()})
+	if err != nil {
+		s.log.Error("close message marshal", zap.Error(err))
+		text = []byte(shutdownCloseReason)
+	}
+
+	s.disconnect(closeReasonShutdown, websocket.CloseGoingAway, string(text))
+}
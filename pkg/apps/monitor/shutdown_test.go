@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInflightWait(t *testing.T) {
+	deliveries := new(inflight)
+	require.NoError(t, deliveries.wait(context.Background()))
+
+	deliveries.add()
+	deliveries.add()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, deliveries.wait(ctx))
+
+	deliveries.done()
+	deliveries.done()
+	assert.NoError(t, deliveries.wait(context.Background()))
+}
+
+func TestMonitorShutdown(t *testing.T) {
+	monitor := newTestMonitor(t)
+
+	stopped := make(chan struct{})
+	go func() {
+		monitor.Run(context.Background())
+		close(stopped)
+	}()
+
+	server := httptest.NewServer(monitor.Handler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"id": "1", "method": "subscribe", "params": {"topic": "event_0", "token": "token", "offset": 7}}`)))
+	_, _, err = conn.ReadMessage()
+	require.NoError(t, err)
+
+	// a delivery in progress
+	monitor.deliveries.add()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		monitor.deliveries.done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, monitor.Shutdown(ctx))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*websocket.CloseError)
+	require.True(t, ok, err)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+
+	closeMessage := new(CloseMessage)
+	require.NoError(t, json.Unmarshal([]byte(closeErr.Text), closeMessage))
+	assert.Equal(t, &CloseMessage{Reason: shutdownCloseReason, Offset: 7}, closeMessage)
+
+	_, response, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Equal(t, websocket.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("monitor run not stopped after shutdown")
+	}
+}
+
+func TestServeWsMonitorStopped(t *testing.T) {
+	monitor := newTestMonitor(t)
+	// the session manager is not running
+	monitor.Close()
+
+	server := httptest.NewServer(monitor.Handler())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err = conn.ReadMessage()
+	require.Error(t, err)
+	assert.False(t, websocket.IsCloseError(err), err)
+	netErr, ok := err.(interface{ Timeout() bool })
+	assert.False(t, ok && netErr.Timeout(), "the connection is not closed")
+}
+
+func TestMonitorShutdownDeadline(t *testing.T) {
+	monitor := newTestMonitor(t)
+	go monitor.Run(context.Background())
+
+	monitor.deliveries.add()
+	defer monitor.deliveries.done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, monitor.Shutdown(ctx))
+}
@@ -20,29 +20,39 @@ func newSendData(data []byte) *dataToSocket {
 	}
 }
 
-func sendPingMessage(conn *websocket.Conn) error {
-	if err := conn.SetWriteDeadline(time.Now().Add(config.session.writeWait)); err != nil {
+func (s *Session) sendPingMessage() error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.monitor.config.session.writeWait)); err != nil {
 		return fmt.Errorf("SetWriteDeadline error: %s", err)
 	}
 
-	if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+	if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 		return fmt.Errorf("writePingMessage error: %s", err)
 	}
 	return nil
 }
 
-func sendCloseMessage(conn *websocket.Conn) error {
-	if err := conn.SetWriteDeadline(time.Now().Add(config.session.writeWait)); err != nil {
+func (s *Session) sendCloseMessage() error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.monitor.config.session.writeWait)); err != nil {
 		return fmt.Errorf("SetWriteDeadline error: %s", err)
 	}
 
-	if err := conn.WriteMessage(websocket.CloseMessage, []byte{}); err != nil {
+	// the close frame is already sent on disconnect
+	if err := s.conn.WriteMessage(websocket.CloseMessage, []byte{}); err != nil && err != websocket.ErrCloseSent {
 		return fmt.Errorf("writeCloseMessage error: %s", err)
 	}
 	return nil
 }
 
-func sendMessage(conn *websocket.Conn, data *dataToSocket) error {
+// sendCloseControl is safe to call concurrently with the write pump
+func (s *Session) sendCloseControl(code int, text string) error {
+	deadline := time.Now().Add(s.monitor.config.session.writeWait)
+	if err := s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline); err != nil {
+		return fmt.Errorf("writeCloseControl error: %s", err)
+	}
+	return nil
+}
+
+func (s *Session) sendMessage(data *dataToSocket) error {
 	data.err = nil
 
 	defer func() {
@@ -50,12 +60,12 @@ func sendMessage(conn *websocket.Conn, data *dataToSocket) error {
 		close(data.done)
 	}()
 
-	if err := conn.SetWriteDeadline(time.Now().Add(config.session.writeWait)); err != nil {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.monitor.config.session.writeWait)); err != nil {
 		data.err = err
 		return fmt.Errorf("SetWriteDeadline error: %s", err)
 	}
 
-	w, err := conn.NextWriter(websocket.TextMessage)
+	w, err := s.conn.NextWriter(websocket.TextMessage)
 	if err != nil {
 		data.err = err
 		return fmt.Errorf("nextWriter error: %s", err)
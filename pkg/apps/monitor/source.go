@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// the default source publishes to the topics without prefix
+	defaultSourceName = ""
+
+	// separates the source name and the topic, eg casino.event_0
+	topicSeparator = "."
+)
+
+// topicName returns the topic of the source
+func topicName(source string, name string) string {
+	if source == defaultSourceName {
+		return name
+	}
+	return source + topicSeparator + name
+}
+
+// parseTopic splits the topic to the source and the topic name
+func parseTopic(topic string) (string, string) {
+	if index := strings.LastIndex(topic, topicSeparator); index >= 0 {
+		return topic[:index], topic[index+len(topicSeparator):]
+	}
+	return defaultSourceName, topic
+}
+
+func eventTopicName(eventType int) string {
+	return fmt.Sprintf("event_%d", eventType)
+}
+
+// eventTopic returns the topic the event is published to
+func eventTopic(e *Event) string {
+	if e.IsSynthetic() {
+		return topicName(e.Source, topicAlertStuckGame)
+	}
+	return topicName(e.Source, eventTopicName(e.EventType))
+}
+
+func (f *DatabaseFilters) match(actAccount string, actName string) bool {
+	return (f.actAccount == nil || *f.actAccount == actAccount) &&
+		(f.actName == nil || *f.actName == actName)
+}
+
+// sourceOf returns the first source matching the action
+func sourceOf(sources []*SourceConfig, actAccount string, actName string) (*SourceConfig, bool) {
+	for _, source := range sources {
+		if source.filter.match(actAccount, actName) {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+func sourceFilters(sources []*SourceConfig) []DatabaseFilters {
+	filters := make([]DatabaseFilters, 0, len(sources))
+	for _, source := range sources {
+		filters = append(filters, source.filter)
+	}
+	return filters
+}
+
+func filterEventsBySource(events []*Event, source string) []*Event {
+	result := events[:0]
+	for _, event := range events {
+		if event.Source == source {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+func filterEventsByTopics(events []*Event, topics []string) []*Event {
+	result := events[:0]
+	for _, event := range events {
+		topic := eventTopic(event)
+		for _, name := range topics {
+			if topic == name {
+				result = append(result, event)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,172 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTopicName(t *testing.T) {
+	assert.Equal(t, "event_0", topicName(defaultSourceName, "event_0"))
+	assert.Equal(t, "casino.event_0", topicName("casino", "event_0"))
+
+	source, name := parseTopic("casino.event_0")
+	assert.Equal(t, "casino", source)
+	assert.Equal(t, "event_0", name)
+
+	source, name = parseTopic("event_0")
+	assert.Equal(t, defaultSourceName, source)
+	assert.Equal(t, "event_0", name)
+
+	assert.Equal(t, "casino.event_3", eventTopic(&Event{EventType: 3, Source: "casino"}))
+	assert.Equal(t, "casino.alert_stuck_game", eventTopic(&Event{EventType: EventAlertStuckGame, Source: "casino"}))
+	assert.True(t, isSyntheticTopic("casino.alert_stuck_game"))
+}
+
+func TestSourceOf(t *testing.T) {
+	casino, send := "casino", "send"
+	sources := []*SourceConfig{
+		{name: "casino", filter: DatabaseFilters{actAccount: &casino, actName: &send}},
+		{name: "any"},
+	}
+
+	source, ok := sourceOf(sources, "casino", "send")
+	require.True(t, ok)
+	assert.Equal(t, "casino", source.name)
+
+	source, ok = sourceOf(sources, "casino", "transfer")
+	require.True(t, ok)
+	assert.Equal(t, "any", source.name)
+
+	_, ok = sourceOf(sources[:1], "other", "send")
+	assert.False(t, ok)
+}
+
+func TestSqlQueryFilters(t *testing.T) {
+	casino, test, send := "casino", "test", "send"
+
+	sql, args := newSqlQuery([]DatabaseFilters{{actAccount: &casino, actName: &send}}).getRow()
+	assert.Contains(t, sql, "WHERE action_trace.act_account=$1 AND action_trace.act_name=$2 ORDER BY")
+	assert.Equal(t, []interface{}{"casino", "send"}, args)
+
+	sql, args = newSqlQuery([]DatabaseFilters{{actAccount: &casino, actName: &send}, {actAccount: &test}}).getRow()
+	assert.Contains(t, sql, "WHERE ((action_trace.act_account=$1 AND action_trace.act_name=$2) OR (action_trace.act_account=$3)) ORDER BY")
+	assert.Equal(t, []interface{}{"casino", "send", "test"}, args)
+
+	query := newSqlQuery([]DatabaseFilters{{actAccount: &casino}, {}})
+	assert.Empty(t, query.key)
+	assert.Empty(t, query.value)
+}
+
+func TestFilterEventsByTopics(t *testing.T) {
+	events := []*Event{
+		{Offset: 1, EventType: 0},
+		{Offset: 2, EventType: 0, Source: "casino"},
+		{Offset: 3, EventType: 1, Source: "casino"},
+		{Offset: 4, EventType: 1},
+	}
+
+	result := filterEventsByTopics(events, []string{"event_0", "casino.event_1"})
+	require.Len(t, result, 2)
+	assert.Equal(t, uint64(1), result[0].Offset)
+	assert.Equal(t, uint64(3), result[1].Offset)
+}
+
+const testSourcesConfigFile = `
+sources:
+  - name: casino
+    account: casino
+    action: send
+  - name: test
+    account: testcasino
+    abi:
+      main: ../../../configs/abi/contract.abi
+      events:
+        0: ../../../configs/abi/event.abi
+`
+
+func TestConfigSources(t *testing.T) {
+	configFile, err := newConfigFile(strings.NewReader(testSourcesConfigFile))
+	require.NoError(t, err)
+
+	config := newConfig()
+	require.NoError(t, config.assign(configFile))
+	require.NoError(t, config.validate())
+
+	sources := config.getSources()
+	require.Len(t, sources, 2)
+	assert.Equal(t, "casino", sources[0].name)
+	assert.Equal(t, "casino", *sources[0].filter.actAccount)
+	assert.Equal(t, "send", *sources[0].filter.actName)
+	assert.Equal(t, &config.abi, sources[0].abi)
+	assert.Nil(t, sources[1].filter.actName)
+	assert.Equal(t, map[int]string{0: "../../../configs/abi/event.abi"}, sources[1].abi.events)
+
+	config.sources[0].name = "ca.sino"
+	config.sources = append(config.sources, &SourceConfig{name: "test"})
+	err = config.validate()
+	require.Error(t, err)
+	assert.Len(t, err.(*ConfigError).Errors, 2)
+
+	config.sources = nil
+	sources = config.getSources()
+	require.Len(t, sources, 1)
+	assert.Equal(t, defaultSourceName, sources[0].name)
+}
+
+// sourceDatabase returns one action trace of the account
+type sourceDatabase struct {
+	DatabaseMock
+	actData    []byte
+	actAccount string
+}
+
+type sourceRow struct {
+	db *sourceDatabase
+}
+
+func (r *sourceRow) Scan(dest ...interface{}) error {
+	*dest[0].(*[]byte) = r.db.actData
+	*dest[1].(*uint64) = 10
+	*dest[2].(**time.Time) = nil
+	*dest[3].(*string) = r.db.actAccount
+	*dest[4].(*string) = "send"
+	return nil
+}
+
+func (db *sourceDatabase) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &sourceRow{db}
+}
+
+func TestFetchEventSource(t *testing.T) {
+	configFile, err := newConfigFile(strings.NewReader(testSourcesConfigFile))
+	require.NoError(t, err)
+
+	monitor := newTestMonitor(t)
+	require.NoError(t, monitor.config.assign(configFile))
+	monitor.abiDecoder, err = NewAbiDecoder(monitor.config, testLoggers.Decoder)
+	require.NoError(t, err)
+
+	data := createStructData(t, 1, 2, "test_string")
+	actionJson := fmt.Sprintf(`{"sender":"test","casino_id":1,"game_id":2,"req_id":3,"event_type":0,"data":"%s"}`, hex.EncodeToString(data))
+	actData, err := monitor.abiDecoder.main.abi.EncodeAction(eos.ActionName(defaultContractActionName), []byte(actionJson))
+	require.NoError(t, err)
+
+	db := &sourceDatabase{actData: actData, actAccount: "testcasino"}
+	event, err := monitor.fetchEvent(context.Background(), db, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "test", event.Source)
+	assert.Equal(t, "test.event_0", eventTopic(event))
+	assert.Equal(t, uint64(10), event.Offset)
+
+	db.actAccount = "other"
+	_, err = monitor.fetchEvent(context.Background(), db, 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
+)
+
+const (
+	// random bytes of the token, hex encoded
+	tokenSize = 32
+	// hex characters of the token hash shown in the logs and the admin API
+	tokenHashPrefixSize = 8
+
+	// the scope <source>.* allows all topics of the source
+	scopeSourceSuffix = topicSeparator + "*"
+
+	sqlSelectUser  = "SELECT id, coalesce(description, ''), scopes, creation_date, expires_at, revoked_at FROM monitor.users WHERE token_hash = $1 AND revoked_at IS NULL"
+	sqlSelectUsers = "SELECT id, coalesce(description, ''), scopes, creation_date, expires_at, revoked_at FROM monitor.users ORDER BY id"
+	sqlInsertUser  = "INSERT INTO monitor.users (token_hash, description, scopes, expires_at, creation_date) VALUES ($1, $2, $3, $4, now()) RETURNING id, creation_date"
+	sqlRevokeUser  = "UPDATE monitor.users SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL RETURNING revoked_at"
+)
+
+// User is the token owner of the shared database, the token itself is not stored
+type User struct {
+	ID     int
+	Label  string
+	Scopes []string
+	// timestamp without time zone of the database
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// tokenHashPrefix identifies the token in the logs and the admin API without exposing it
+func tokenHashPrefix(token string) string {
+	return hashToken(token)[:tokenHashPrefixSize]
+}
+
+func newToken() (string, error) {
+	data := make([]byte, tokenSize)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+func (u *User) expired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// Status returns active, expired or revoked
+func (u *User) Status(now time.Time) string {
+	switch {
+	case u.RevokedAt != nil:
+		return "revoked"
+	case u.expired(now):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// allowed returns true if the topic is in the scopes, a user without scopes is allowed all topics
+func (u *User) allowed(topic string) bool {
+	if len(u.Scopes) == 0 {
+		return true
+	}
+
+	source, _ := parseTopic(topic)
+	for _, scope := range u.Scopes {
+		if scope == topic || (strings.HasSuffix(scope, scopeSourceSuffix) && strings.TrimSuffix(scope, scopeSourceSuffix) == source) {
+			return true
+		}
+	}
+	return false
+}
+
+func scanUser(row pgx.Row) (*User, error) {
+	user := new(User)
+	err := row.Scan(&user.ID, &user.Label, &user.Scopes, &user.CreatedAt, &user.ExpiresAt, &user.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateToken stores the hash of a new random token, expiresAt and scopes are optional.
+// The token is returned only once.
+func CreateToken(ctx context.Context, db DatabaseConnect, label string, expiresAt *time.Time, scopes []string) (string, *User, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if scopes == nil {
+		scopes = make([]string, 0)
+	}
+
+	user := &User{Label: label, Scopes: scopes, ExpiresAt: expiresAt}
+	err = db.QueryRow(ctx, sqlInsertUser, hashToken(token), label, scopes, expiresAt).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
+
+// ListTokens returns the users including the expired and revoked ones
+func ListTokens(ctx context.Context, db DatabaseConnect) ([]*User, error) {
+	rows, err := db.Query(ctx, sqlSelectUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// RevokeToken marks the token of the user revoked, returns pgx.ErrNoRows if the user is not found or already revoked
+func RevokeToken(ctx context.Context, db DatabaseConnect, ID int) error {
+	var revokedAt time.Time
+	return db.QueryRow(ctx, sqlRevokeUser, ID).Scan(&revokedAt)
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// userDatabase stores one user by the token hash
+type userDatabase struct {
+	DatabaseMock
+	user *User
+	hash string
+	args []interface{}
+}
+
+type userRow struct {
+	db  *userDatabase
+	sql string
+}
+
+func (r *userRow) Scan(dest ...interface{}) error {
+	if r.sql == sqlInsertUser {
+		r.db.hash = r.db.args[0].(string)
+		*dest[0].(*int) = 1
+		*dest[1].(*time.Time) = time.Now()
+		return nil
+	}
+
+	if r.db.user == nil || r.db.args[0] != r.db.hash {
+		return pgx.ErrNoRows
+	}
+	*dest[0].(*int) = r.db.user.ID
+	*dest[2].(*[]string) = r.db.user.Scopes
+	*dest[4].(**time.Time) = r.db.user.ExpiresAt
+	return nil
+}
+
+func (db *userDatabase) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.args = args
+	return &userRow{db, sql}
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hashToken(""))
+
+	token, err := newToken()
+	require.NoError(t, err)
+	assert.Len(t, token, 2*tokenSize)
+
+	other, err := newToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestCreateAndFindToken(t *testing.T) {
+	db := new(userDatabase)
+	expiresAt := time.Now().Add(time.Hour)
+
+	token, user, err := CreateToken(context.Background(), db, "test", &expiresAt, []string{"casino.*"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, hashToken(token), db.hash)
+	assert.NotContains(t, db.args, token)
+
+	db.user = user
+	found, err := findUser(context.Background(), db, nil, token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"casino.*"}, found.Scopes)
+
+	_, err = findUser(context.Background(), db, nil, "other")
+	assert.Equal(t, errUserNotExists, err)
+
+	expiresAt = time.Now().Add(-time.Second)
+	_, err = findUser(context.Background(), db, nil, token)
+	assert.Equal(t, errTokenExpired, err)
+}
+
+func TestCheckTokenScopes(t *testing.T) {
+	db := new(userDatabase)
+	monitor := newTestMonitor(t)
+	monitor.config.skipTokenCheck = false
+	monitor.sharedPool = db
+
+	token, user, err := CreateToken(context.Background(), db, "", nil, []string{"event_0", "casino.*"})
+	require.NoError(t, err)
+	db.user = user
+
+	assert.NoError(t, monitor.checkToken(context.Background(), token, "event_0", "casino.event_1"))
+	assert.Equal(t, errTopicNotAllowed, monitor.checkToken(context.Background(), token, "event_1"))
+	assert.Equal(t, errTopicNotAllowed, monitor.checkToken(context.Background(), token, "test.event_0"))
+	assert.Equal(t, errUserNotExists, monitor.checkToken(context.Background(), "other"))
+
+	user.Scopes = nil
+	assert.NoError(t, monitor.checkToken(context.Background(), token, "test.event_0"))
+
+	// the session keeps the user and the hash prefix of the token only
+	found, err := monitor.authorize(context.Background(), token, "event_0")
+	require.NoError(t, err)
+	session := newSession(monitor, nil)
+	session.setToken(token, found)
+	tokenHash, sessionUser := session.Token()
+	assert.Equal(t, hashToken(token)[:tokenHashPrefixSize], tokenHash)
+	assert.Equal(t, user.ID, sessionUser.ID)
+}
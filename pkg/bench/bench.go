@@ -0,0 +1,305 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/protocol"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Time allowed to read the next message or ping from the monitor
+	defaultReadTimeout = 90 * time.Second
+
+	// Time allowed to write a message to the monitor
+	defaultWriteWait = 10 * time.Second
+
+	methodBatchSubscribe = "batchSubscribe"
+)
+
+type Config struct {
+	// Monitor websocket url, eg ws://localhost:8888/
+	URL      string
+	Token    string
+	Sessions int
+	// session i subscribes to the topics of Mixes[i % len(Mixes)]
+	Mixes [][]string
+	// session i subscribes from Offsets[i % len(Offsets)], from 0 if empty
+	Offsets []uint64
+	// total time of the benchmark including the ramp up
+	Duration time.Duration
+	// the sessions are opened evenly during the ramp up
+	RampUp      time.Duration
+	ReadTimeout time.Duration
+}
+
+// Latency percentiles of the delivery, the time from the notification of the monitor about the event to its receiving
+type Latency struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+type Report struct {
+	Sessions int
+	// sessions failed to connect or subscribe
+	Failed int
+	// sessions closed by the monitor or broken before the end
+	Dropped int
+	Events  int
+	// events notified during the benchmark, the latency is measured for them only,
+	// the events sent from the history have no notification time
+	Live     int
+	Duration time.Duration
+	// events received by all sessions per second
+	Throughput float64
+	Latency    Latency
+	// first error of the failed and dropped sessions
+	Errors []string
+}
+
+type batchSubscribeParams struct {
+	Token  string   `json:"token"`
+	Topics []string `json:"topics"`
+	Offset uint64   `json:"offset"`
+}
+
+type requestMessage struct {
+	ID     string                `json:"id"`
+	Method string                `json:"method"`
+	Params *batchSubscribeParams `json:"params"`
+}
+
+type sessionResult struct {
+	failed    bool
+	dropped   bool
+	events    int
+	latencies []time.Duration
+	err       error
+}
+
+func (c *Config) validate() error {
+	switch {
+	case c.URL == "":
+		return errors.New("empty url")
+	case c.Sessions <= 0:
+		return errors.New("sessions must be positive")
+	case len(c.Mixes) == 0:
+		return errors.New("no topic mixes")
+	case c.Duration <= 0:
+		return errors.New("duration must be positive")
+	case c.RampUp < 0 || c.RampUp >= c.Duration:
+		return errors.New("ramp up must be from 0 to duration")
+	}
+
+	for i, mix := range c.Mixes {
+		if len(mix) == 0 {
+			return fmt.Errorf("topic mix %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// Run opens the sessions and receives the events until the duration is over or parentContext is done
+func Run(parentContext context.Context, config Config, log *zap.Logger) (*Report, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = defaultReadTimeout
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(parentContext, start.Add(config.Duration))
+	defer cancel()
+
+	results := make([]*sessionResult, config.Sessions)
+	opened := 0
+	var wg sync.WaitGroup
+	for i := 0; i < config.Sessions; i++ {
+		delay := config.RampUp * time.Duration(i) / time.Duration(config.Sessions)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(start.Add(delay))):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		opened++
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runSession(ctx, &config, i)
+			if err := results[i].err; err != nil {
+				log.Debug("session error", zap.Int("session", i), zap.Error(err))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return newReport(results[:opened], time.Since(start)), nil
+}
+
+func runSession(ctx context.Context, config *Config, index int) *sessionResult {
+	result := new(sessionResult)
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, config.URL, nil)
+	if err != nil {
+		result.failed, result.err = true, fmt.Errorf("dial error: %s", err)
+		return result
+	}
+
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		_ = conn.Close()
+	}()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetPingHandler(func(data string) error {
+		if err := conn.SetReadDeadline(time.Now().Add(config.ReadTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(defaultWriteWait))
+	})
+
+	request := &requestMessage{
+		ID:     strconv.Itoa(index),
+		Method: methodBatchSubscribe,
+		Params: &batchSubscribeParams{
+			Token:  config.Token,
+			Topics: config.Mixes[index%len(config.Mixes)],
+		},
+	}
+	if len(config.Offsets) != 0 {
+		request.Params.Offset = config.Offsets[index%len(config.Offsets)]
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(defaultWriteWait)); err == nil {
+		err = conn.WriteJSON(request)
+	}
+	if err != nil {
+		result.failed, result.err = true, fmt.Errorf("write subscribe error: %s", err)
+		return result
+	}
+
+	subscribed := false
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(config.ReadTimeout)); err != nil {
+			result.dropped, result.err = true, err
+			return result
+		}
+
+		_, message, err := conn.ReadMessage()
+		received := time.Now()
+		if ctx.Err() != nil {
+			// the benchmark is over
+			result.failed = !subscribed
+			return result
+		}
+		if err != nil {
+			result.failed, result.dropped = !subscribed, subscribed
+			result.err = fmt.Errorf("read error: %s", err)
+			return result
+		}
+
+		response := new(protocol.ResponseMessage)
+		if err := json.Unmarshal(message, response); err != nil {
+			result.dropped, result.err = true, fmt.Errorf("parse response error: %s", err)
+			return result
+		}
+
+		if response.ID != nil {
+			if response.Error != nil {
+				result.failed, result.err = true, fmt.Errorf("subscribe error %d: %s", response.Error.Code, response.Error.Message)
+				return result
+			}
+			subscribed = true
+			continue
+		}
+
+		eventMessage := new(protocol.EventMessage)
+		if err := json.Unmarshal(response.Result, eventMessage); err != nil {
+			result.dropped, result.err = true, fmt.Errorf("parse events error: %s", err)
+			return result
+		}
+
+		for _, event := range eventMessage.Events {
+			result.events++
+			if event.NotifiedAt != nil {
+				result.latencies = append(result.latencies, received.Sub(*event.NotifiedAt))
+			}
+		}
+	}
+}
+
+// percentile of the sorted latencies
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	index := int(float64(len(latencies))*p+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(latencies) {
+		index = len(latencies) - 1
+	}
+	return latencies[index]
+}
+
+func newReport(results []*sessionResult, duration time.Duration) *Report {
+	report := &Report{Sessions: len(results), Duration: duration, Errors: make([]string, 0)}
+	errorSeen := make(map[string]bool)
+
+	latencies := make([]time.Duration, 0)
+	for _, result := range results {
+		if result.failed {
+			report.Failed++
+		}
+		if result.dropped {
+			report.Dropped++
+		}
+		if result.err != nil && !errorSeen[result.err.Error()] {
+			errorSeen[result.err.Error()] = true
+			report.Errors = append(report.Errors, result.err.Error())
+		}
+		report.Events += result.events
+		latencies = append(latencies, result.latencies...)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	report.Live = len(latencies)
+	report.Latency = Latency{
+		P50: percentile(latencies, 0.50),
+		P90: percentile(latencies, 0.90),
+		P99: percentile(latencies, 0.99),
+		Max: percentile(latencies, 1),
+	}
+	if duration > 0 {
+		report.Throughput = float64(report.Events) / duration.Seconds()
+	}
+	return report
+}
@@ -0,0 +1,153 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encodeActData(t *testing.T, gameID uint64) []byte {
+	var buffer bytes.Buffer
+	err := eos.NewEncoder(&buffer).Encode(&struct {
+		A uint64
+		B uint32
+		C string
+	}{1, 2, "test"})
+	require.NoError(t, err)
+
+	f, err := os.Open("../../configs/abi/contract.abi")
+	require.NoError(t, err)
+	defer f.Close()
+
+	abi, err := eos.NewABI(f)
+	require.NoError(t, err)
+
+	action := fmt.Sprintf(`{"sender":"test","casino_id":1,"game_id":%d,"req_id":1,"event_type":0,"data":"%s"}`,
+		gameID, hex.EncodeToString(buffer.Bytes()))
+	data, err := abi.EncodeAction("send", []byte(action))
+	require.NoError(t, err)
+
+	return data
+}
+
+const testConfigFile = `
+server:
+  addr: :0
+skipTokenCheck: true
+abi:
+  main: %s
+  events:
+    0: %s
+`
+
+func newTestServer(t *testing.T, db monitor.DatabasePool) (*httptest.Server, func()) {
+	contractABI, err := filepath.Abs("../../configs/abi/contract.abi")
+	require.NoError(t, err)
+	eventABI, err := filepath.Abs("../../configs/abi/event.abi")
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "monitor-config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = fmt.Fprintf(f, testConfigFile, contractABI, eventABI)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	config, err := monitor.LoadConfig(f.Name())
+	require.NoError(t, err)
+
+	decoder, err := monitor.NewAbiDecoder(config, nil)
+	require.NoError(t, err)
+
+	m := monitor.NewMonitor(config, decoder, db, db, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	go m.Run(ctx)
+
+	server := httptest.NewServer(m.Handler())
+	return server, func() {
+		server.Close()
+		cancel()
+		m.Close()
+	}
+}
+
+func TestRun(t *testing.T) {
+	db := monitor.NewDatabaseMemory()
+	db.AddBlock(1, time.Now())
+	db.AddAction(&monitor.DatabaseMemoryAction{Offset: 1, BlockNum: 1, ActAccount: "casino", ActName: "send", ActData: encodeActData(t, 1)})
+	server, teardown := newTestServer(t, db)
+	defer teardown()
+
+	// the live actions after all sessions subscribe
+	live := [][]byte{encodeActData(t, 2), encodeActData(t, 3)}
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		db.AddBlock(2, time.Now())
+		for i, actData := range live {
+			db.AddAction(&monitor.DatabaseMemoryAction{Offset: uint64(i + 2), BlockNum: 2, ActAccount: "casino", ActName: "send", ActData: actData})
+		}
+	}()
+
+	report, err := Run(context.Background(), Config{
+		URL:      "ws" + strings.TrimPrefix(server.URL, "http") + "/",
+		Token:    "test",
+		Sessions: 4,
+		Mixes:    [][]string{{"event_0"}, {"event_1"}},
+		Duration: 500 * time.Millisecond,
+		RampUp:   100 * time.Millisecond,
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Sessions)
+	assert.Equal(t, 0, report.Failed)
+	assert.Equal(t, 0, report.Dropped)
+	// the first action is sent from the history
+	assert.Equal(t, 6, report.Events)
+	assert.Equal(t, 4, report.Live)
+	assert.True(t, report.Latency.P50 > 0)
+	assert.True(t, report.Latency.Max >= report.Latency.P99)
+	assert.True(t, report.Throughput > 0)
+	assert.Empty(t, report.Errors)
+}
+
+func TestRunFailed(t *testing.T) {
+	server := httptest.NewServer(nil)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/"
+	server.Close()
+
+	report, err := Run(context.Background(), Config{URL: url, Sessions: 2, Mixes: [][]string{{"event_0"}}, Duration: 100 * time.Millisecond}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Failed)
+	assert.Len(t, report.Errors, 1)
+
+	_, err = Run(context.Background(), Config{URL: url, Sessions: 1, Mixes: [][]string{{}}, Duration: time.Second}, nil)
+	assert.Error(t, err)
+
+	_, err = Run(context.Background(), Config{URL: url, Sessions: 1, Mixes: [][]string{{"event_0"}}, Duration: time.Second, RampUp: time.Second}, nil)
+	assert.Error(t, err)
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 0.5))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 0.99))
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 1))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.5))
+}
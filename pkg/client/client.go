@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/protocol"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Delay before the first reconnect, doubled after every failed attempt
+	defaultMinBackoff = 500 * time.Millisecond
+
+	// Maximum delay between reconnects
+	defaultMaxBackoff = 30 * time.Second
+
+	// Time allowed to read the next message or ping from the monitor.
+	// Must be greater than the monitor ping period.
+	defaultReadTimeout = 90 * time.Second
+
+	// Time allowed to write a message to the monitor.
+	defaultWriteWait = 10 * time.Second
+
+	// Size of the events channel buffer
+	defaultEventsBufferSize = 512
+
+	methodBatchSubscribe = "batchSubscribe"
+)
+
+type Config struct {
+	// Monitor websocket url, eg ws://localhost:8888/
+	URL    string
+	Token  string
+	Topics []string
+	// Offset of the first event to receive
+	Offset uint64
+
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	ReadTimeout time.Duration
+}
+
+// SubscribeError is returned by Run when the monitor rejects the subscription, eg the token is unknown
+type SubscribeError struct {
+	Code    int
+	Message string
+}
+
+func (e *SubscribeError) Error() string {
+	return fmt.Sprintf("subscribe error %d: %s", e.Code, e.Message)
+}
+
+type batchSubscribeParams struct {
+	Token  string   `json:"token"`
+	Topics []string `json:"topics"`
+	Offset uint64   `json:"offset"`
+}
+
+type requestMessage struct {
+	ID     string                `json:"id"`
+	Method string                `json:"method"`
+	Params *batchSubscribeParams `json:"params"`
+}
+
+// Client receives events from the monitor and reconnects with backoff,
+// resuming from the offset after the last received event
+type Client struct {
+	config Config
+	log    *zap.Logger
+	events chan *protocol.Event
+
+	sync.Mutex
+	offset    uint64
+	requestID uint64
+}
+
+func New(config Config, log *zap.Logger) *Client {
+	if config.MinBackoff == 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = defaultReadTimeout
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	return &Client{
+		config: config,
+		log:    log,
+		events: make(chan *protocol.Event, defaultEventsBufferSize),
+		offset: config.Offset,
+	}
+}
+
+// Events returns the channel of received events, it is closed when Run returns
+func (c *Client) Events() <-chan *protocol.Event {
+	return c.events
+}
+
+// Offset returns the offset the client subscribes from on the next connect
+func (c *Client) Offset() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.offset
+}
+
+func (c *Client) setOffset(offset uint64) {
+	c.Lock()
+	defer c.Unlock()
+	c.offset = offset
+}
+
+func (c *Client) nextRequestID() string {
+	c.Lock()
+	defer c.Unlock()
+	c.requestID++
+	return strconv.FormatUint(c.requestID, 10)
+}
+
+// Run connects to the monitor and reconnects until parentContext is done.
+// Returns nil when parentContext is done or *SubscribeError if the monitor rejects the subscription.
+func (c *Client) Run(parentContext context.Context) error {
+	defer close(c.events)
+
+	backoff := c.config.MinBackoff
+	for {
+		subscribed, err := c.connect(parentContext)
+		if parentContext.Err() != nil {
+			return nil
+		}
+
+		if _, ok := err.(*SubscribeError); ok {
+			return err
+		}
+
+		if subscribed {
+			backoff = c.config.MinBackoff
+		}
+
+		c.log.Info("reconnect",
+			zap.Error(err),
+			zap.Duration("backoff", backoff),
+			zap.Uint64("offset", c.Offset()),
+		)
+
+		select {
+		case <-parentContext.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.config.MaxBackoff {
+			backoff = c.config.MaxBackoff
+		}
+	}
+}
+
+// connect runs one connection until it fails, returns true if the subscription succeeded
+func (c *Client) connect(parentContext context.Context) (bool, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(parentContext, c.config.URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("dial error: %s", err)
+	}
+
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		_ = conn.Close()
+	}()
+
+	go func() {
+		select {
+		case <-parentContext.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetPingHandler(func(data string) error {
+		if err := conn.SetReadDeadline(time.Now().Add(c.config.ReadTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(defaultWriteWait))
+	})
+
+	request := &requestMessage{
+		ID:     c.nextRequestID(),
+		Method: methodBatchSubscribe,
+		Params: &batchSubscribeParams{
+			Token:  c.config.Token,
+			Topics: c.config.Topics,
+			Offset: c.Offset(),
+		},
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(defaultWriteWait)); err != nil {
+		return false, err
+	}
+	if err := conn.WriteJSON(request); err != nil {
+		return false, fmt.Errorf("write subscribe error: %s", err)
+	}
+
+	subscribed := false
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(c.config.ReadTimeout)); err != nil {
+			return subscribed, err
+		}
+
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if closeErr, ok := err.(*websocket.CloseError); ok && closeErr.Code == websocket.CloseGoingAway {
+				c.logGoingAway(closeErr.Text)
+			}
+			return subscribed, fmt.Errorf("read error: %s", err)
+		}
+
+		response := new(protocol.ResponseMessage)
+		if err := json.Unmarshal(message, response); err != nil {
+			return subscribed, fmt.Errorf("parse response error: %s", err)
+		}
+
+		if response.ID != nil {
+			if *response.ID != request.ID {
+				continue
+			}
+
+			if response.Error != nil {
+				return false, &SubscribeError{response.Error.Code, response.Error.Message}
+			}
+
+			subscribed = true
+			c.log.Debug("subscribed", zap.Strings("topics", c.config.Topics), zap.Uint64("offset", request.Params.Offset))
+			continue
+		}
+
+		eventMessage := new(protocol.EventMessage)
+		if err := json.Unmarshal(response.Result, eventMessage); err != nil {
+			return subscribed, fmt.Errorf("parse events error: %s", err)
+		}
+
+		if err := c.deliver(parentContext, eventMessage.Events); err != nil {
+			return subscribed, err
+		}
+
+		if err := c.deliverAlerts(parentContext, eventMessage.Alerts); err != nil {
+			return subscribed, err
+		}
+	}
+}
+
+// logGoingAway logs the close message of the monitor shutdown
+func (c *Client) logGoingAway(text string) {
+	closeMessage := new(protocol.CloseMessage)
+	if err := json.Unmarshal([]byte(text), closeMessage); err != nil {
+		c.log.Info("monitor going away", zap.String("reason", text))
+		return
+	}
+
+	c.log.Info("monitor going away",
+		zap.String("reason", closeMessage.Reason),
+		zap.Uint64("monitor.offset", closeMessage.Offset),
+		zap.Uint64("offset", c.Offset()),
+	)
+}
+
+func (c *Client) deliver(parentContext context.Context, events []*protocol.Event) error {
+	for _, event := range events {
+		// the monitor may send again events already received before reconnect
+		if event.Offset < c.Offset() {
+			continue
+		}
+
+		select {
+		case <-parentContext.Done():
+			return parentContext.Err()
+		case c.events <- event:
+			c.setOffset(event.Offset + 1)
+		}
+	}
+
+	return nil
+}
+
+// deliverAlerts passes the synthetic events, they have no offset and are not sent again
+func (c *Client) deliverAlerts(parentContext context.Context, alerts []*protocol.Event) error {
+	for _, alert := range alerts {
+		select {
+		case <-parentContext.Done():
+			return parentContext.Err()
+		case c.events <- alert:
+		}
+	}
+
+	return nil
+}
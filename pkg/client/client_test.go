@@ -0,0 +1,289 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/DaoCasino/platform-action-monitor/pkg/apps/monitor"
+	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testActionTrace struct {
+	actData []byte
+	offset  uint64
+}
+
+// DatabaseMock style fake: returns the stored action traces from the requested offset
+// and accepts every token
+type testDatabase struct {
+	sync.Mutex
+	traces  []*testActionTrace
+	offsets []uint64
+}
+
+type testRow struct{}
+
+func (r *testRow) Scan(dest ...interface{}) error {
+	*dest[0].(*int) = 1
+	return nil
+}
+
+type testRows struct {
+	traces []*testActionTrace
+	index  int
+}
+
+func (r *testRows) Next() bool {
+	r.index++
+	return r.index <= len(r.traces)
+}
+func (r *testRows) Close()     {}
+func (r *testRows) Err() error { return nil }
+func (r *testRows) CommandTag() pgconn.CommandTag {
+	return nil
+}
+func (r *testRows) FieldDescriptions() []pgproto3.FieldDescription {
+	return nil
+}
+func (r *testRows) Scan(dest ...interface{}) error {
+	trace := r.traces[r.index-1]
+	*dest[0].(*[]byte) = trace.actData
+	*dest[1].(*uint64) = trace.offset
+	*dest[2].(*time.Time) = time.Now()
+	return nil
+}
+func (r *testRows) Values() ([]interface{}, error) {
+	return nil, nil
+}
+func (r *testRows) RawValues() [][]byte {
+	return nil
+}
+
+func (d *testDatabase) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return new(testRow)
+}
+
+func (d *testDatabase) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	offset := args[len(args)-1].(uint64)
+	d.offsets = append(d.offsets, offset)
+
+	rows := new(testRows)
+	for _, trace := range d.traces {
+		if trace.offset >= offset {
+			rows.traces = append(rows.traces, trace)
+		}
+	}
+	return rows, nil
+}
+
+func (d *testDatabase) Close() {}
+
+func (d *testDatabase) add(t *testing.T, offset uint64) {
+	d.Lock()
+	defer d.Unlock()
+	d.traces = append(d.traces, &testActionTrace{encodeActData(t, offset), offset})
+}
+
+func (d *testDatabase) queriedOffsets() []uint64 {
+	d.Lock()
+	defer d.Unlock()
+	return append([]uint64(nil), d.offsets...)
+}
+
+func encodeActData(t *testing.T, gameID uint64) []byte {
+	var buffer bytes.Buffer
+	err := eos.NewEncoder(&buffer).Encode(&struct {
+		A uint64
+		B uint32
+		C string
+	}{1, 2, "test"})
+	require.NoError(t, err)
+
+	f, err := os.Open("../../configs/abi/contract.abi")
+	require.NoError(t, err)
+	defer f.Close()
+
+	abi, err := eos.NewABI(f)
+	require.NoError(t, err)
+
+	action := fmt.Sprintf(`{"sender":"test","casino_id":1,"game_id":%d,"req_id":1,"event_type":0,"data":"%s"}`,
+		gameID, hex.EncodeToString(buffer.Bytes()))
+	data, err := abi.EncodeAction("send", []byte(action))
+	require.NoError(t, err)
+
+	return data
+}
+
+const testConfigFile = `
+server:
+  addr: :0
+session:
+  writeWait: 10s
+  pongWait: 60s
+  maxEventsInMessage: 50
+upgrader:
+  readBufferSize: 1024
+  writeBufferSize: 1024
+abi:
+  main: %s
+  events:
+    0: %s
+`
+
+func newTestMonitor(t *testing.T, db monitor.DatabasePool, sharedDb monitor.DatabasePool) *monitor.Monitor {
+	contractABI, err := filepath.Abs("../../configs/abi/contract.abi")
+	require.NoError(t, err)
+	eventABI, err := filepath.Abs("../../configs/abi/event.abi")
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "monitor-config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = fmt.Fprintf(f, testConfigFile, contractABI, eventABI)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	config, err := monitor.LoadConfig(f.Name())
+	require.NoError(t, err)
+
+	decoder, err := monitor.NewAbiDecoder(config, nil)
+	require.NoError(t, err)
+
+	return monitor.NewMonitor(config, decoder, db, sharedDb, nil, nil)
+}
+
+// hijackRecorder keeps the websocket connections to break them from the test
+type hijackRecorder struct {
+	http.ResponseWriter
+	conns chan net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.conns <- conn
+	}
+	return conn, rw, err
+}
+
+func receive(t *testing.T, c *Client) uint64 {
+	select {
+	case event := <-c.Events():
+		return event.GameID
+	case <-time.After(5 * time.Second):
+		t.Fatal("event timeout")
+	}
+	return 0
+}
+
+func TestClientReconnect(t *testing.T) {
+	db := new(testDatabase)
+	db.add(t, 1)
+	db.add(t, 2)
+
+	m := newTestMonitor(t, db, db)
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	conns := make(chan net.Conn, 10)
+	handler := m.Handler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(&hijackRecorder{w, conns}, r)
+	}))
+	defer server.Close()
+
+	c := New(Config{
+		URL:        "ws" + strings.TrimPrefix(server.URL, "http") + "/",
+		Token:      "test",
+		Topics:     []string{"event_0"},
+		MinBackoff: 10 * time.Millisecond,
+	}, nil)
+
+	runResult := make(chan error)
+	go func() {
+		runResult <- c.Run(ctx)
+	}()
+
+	assert.Equal(t, uint64(1), receive(t, c))
+	assert.Equal(t, uint64(2), receive(t, c))
+	assert.Equal(t, uint64(3), c.Offset())
+
+	db.add(t, 3)
+	conn := <-conns
+	require.NoError(t, conn.Close())
+
+	assert.Equal(t, uint64(3), receive(t, c))
+	assert.Equal(t, uint64(4), c.Offset())
+	assert.Equal(t, []uint64{0, 3}, db.queriedOffsets())
+
+	cancel()
+	select {
+	case err := <-runResult:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("client run not stopped")
+	}
+}
+
+// rejects every token
+type rejectDatabase struct {
+	testDatabase
+}
+
+type rejectRow struct{}
+
+// no user with the token hash
+func (r *rejectRow) Scan(dest ...interface{}) error {
+	return pgx.ErrNoRows
+}
+
+func (d *rejectDatabase) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return new(rejectRow)
+}
+
+func TestClientSubscribeError(t *testing.T) {
+	db := new(testDatabase)
+	m := newTestMonitor(t, db, new(rejectDatabase))
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	c := New(Config{
+		URL:    "ws" + strings.TrimPrefix(server.URL, "http") + "/",
+		Token:  "test",
+		Topics: []string{"event_0"},
+	}, nil)
+
+	err := c.Run(ctx)
+	require.IsType(t, &SubscribeError{}, err)
+	assert.Equal(t, "user not exist", err.(*SubscribeError).Message)
+}
@@ -0,0 +1,237 @@
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// dbmate format of the migration files
+	markerUp   = "-- migrate:up"
+	markerDown = "-- migrate:down"
+
+	// the table of dbmate, the databases migrated by dbmate keep their versions
+	sqlCreateVersions = "CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(255) PRIMARY KEY)"
+	sqlSelectVersions = "SELECT version FROM schema_migrations"
+	sqlInsertVersion  = "INSERT INTO schema_migrations (version) VALUES ($1)"
+	sqlDeleteVersion  = "DELETE FROM schema_migrations WHERE version = $1"
+)
+
+// Migration is the file <version>_<name>.sql with the up and down sections
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	*Migration
+	Applied bool
+}
+
+// Conn is implemented by *pgx.Conn
+type Conn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, optionsAndArgs ...interface{}) (pgx.Rows, error)
+}
+
+// Load returns the migrations of the directory sorted by version
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]*Migration, 0, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, err := parse(path.Base(file), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %s", file, err)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %s", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func parse(filename string, data string) (*Migration, error) {
+	base := strings.TrimSuffix(filename, ".sql")
+	parts := strings.SplitN(base, "_", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("no version in the file name")
+	}
+
+	migration := &Migration{Version: parts[0]}
+	if len(parts) == 2 {
+		migration.Name = parts[1]
+	}
+
+	var up, down strings.Builder
+	var section *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, markerUp):
+			section = &up
+		case strings.HasPrefix(line, markerDown):
+			section = &down
+		case section != nil:
+			section.WriteString(line)
+			section.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	migration.Up, migration.Down = strings.TrimSpace(up.String()), strings.TrimSpace(down.String())
+	if migration.Up == "" {
+		return nil, fmt.Errorf("no %s section", markerUp)
+	}
+	return migration, nil
+}
+
+// Migrator applies the migrations in a transaction each and tracks the versions in schema_migrations
+type Migrator struct {
+	conn       Conn
+	migrations []*Migration
+}
+
+func NewMigrator(conn Conn, migrations []*Migration) *Migrator {
+	return &Migrator{conn, migrations}
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[string]bool, error) {
+	if _, err := m.conn.Exec(ctx, sqlCreateVersions); err != nil {
+		return nil, fmt.Errorf("create schema_migrations error: %s", err)
+	}
+
+	rows, err := m.conn.Query(ctx, sqlSelectVersions)
+	if err != nil {
+		return nil, fmt.Errorf("select schema_migrations error: %s", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions[version] = true
+	}
+	return versions, rows.Err()
+}
+
+// Status returns the migrations with the applied flag
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return status(m.migrations, versions), nil
+}
+
+func status(migrations []*Migration, versions map[string]bool) []*MigrationStatus {
+	result := make([]*MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, &MigrationStatus{migration, versions[migration.Version]})
+	}
+	return result
+}
+
+// Up applies the pending migrations, returns the applied ones
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Migration, 0)
+	for _, migration := range m.migrations {
+		if versions[migration.Version] {
+			continue
+		}
+
+		if err := m.run(ctx, migration.Up, sqlInsertVersion, migration.Version); err != nil {
+			return result, fmt.Errorf("migration %s up error: %s", migration.Version, err)
+		}
+		result = append(result, migration)
+	}
+	return result, nil
+}
+
+// Down rolls back the last applied migration, returns nil if nothing is applied
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migration := last(m.migrations, versions)
+	if migration == nil {
+		return nil, nil
+	}
+	if migration.Down == "" {
+		return nil, fmt.Errorf("migration %s has no %s section", migration.Version, markerDown)
+	}
+
+	if err := m.run(ctx, migration.Down, sqlDeleteVersion, migration.Version); err != nil {
+		return nil, fmt.Errorf("migration %s down error: %s", migration.Version, err)
+	}
+	return migration, nil
+}
+
+func last(migrations []*Migration, versions map[string]bool) *Migration {
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if versions[migrations[i].Version] {
+			return migrations[i]
+		}
+	}
+	return nil
+}
+
+// run executes the migration script and updates the version in one transaction
+func (m *Migrator) run(ctx context.Context, script string, sqlVersion string, version string) error {
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// without arguments the script is sent by the simple protocol, several statements are allowed
+	if _, err = tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, sqlVersion, version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
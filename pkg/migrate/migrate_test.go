@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"github.com/DaoCasino/platform-action-monitor/db"
+	"github.com/DaoCasino/platform-action-monitor/shared-db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := Load(db.Migrations, "migrations")
+	require.NoError(t, err)
+	require.True(t, len(migrations) >= 2)
+	assert.Equal(t, "20200420174223", migrations[0].Version)
+	assert.Equal(t, "notify_trigger", migrations[0].Name)
+	assert.Contains(t, migrations[0].Up, "CREATE TRIGGER action_trace_insert")
+	assert.Contains(t, migrations[0].Down, "DROP TRIGGER action_trace_insert")
+	assert.NotContains(t, migrations[0].Up, markerDown)
+
+	migrations, err = Load(shareddb.Migrations, "migrations")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Contains(t, migrations[0].Up, "CREATE TABLE monitor.users")
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load(fstest.MapFS{"migrations/1_test.sql": {Data: []byte("-- migrate:down\nSELECT 1;")}}, "migrations")
+	assert.Error(t, err)
+
+	_, err = Load(fstest.MapFS{"migrations/_test.sql": {Data: []byte("-- migrate:up\nSELECT 1;")}}, "migrations")
+	assert.Error(t, err)
+
+	_, err = Load(fstest.MapFS{
+		"migrations/1_a.sql": {Data: []byte("-- migrate:up\nSELECT 1;")},
+		"migrations/1_b.sql": {Data: []byte("-- migrate:up\nSELECT 2;")},
+	}, "migrations")
+	assert.Error(t, err)
+}
+
+func TestStatus(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"migrations/2_b.sql": {Data: []byte("-- migrate:up\nSELECT 2;\n-- migrate:down\nSELECT -2;")},
+		"migrations/1_a.sql": {Data: []byte("-- migrate:up transaction:true\nSELECT 1;")},
+	}, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "1", migrations[0].Version)
+	assert.Equal(t, "SELECT 1;", migrations[0].Up)
+	assert.Equal(t, "SELECT -2;", migrations[1].Down)
+
+	result := status(migrations, map[string]bool{"1": true, "other": true})
+	assert.True(t, result[0].Applied)
+	assert.False(t, result[1].Applied)
+
+	assert.Equal(t, migrations[0], last(migrations, map[string]bool{"1": true}))
+	assert.Equal(t, migrations[1], last(migrations, map[string]bool{"1": true, "2": true}))
+	assert.Nil(t, last(migrations, map[string]bool{"other": true}))
+}
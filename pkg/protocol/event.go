@@ -0,0 +1,27 @@
+// Package protocol has the websocket messages of the monitor, it is shared by the monitor and the clients
+package protocol
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Event struct {
+	Offset    uint64          `json:"offset"`
+	Sender    string          `json:"sender"`
+	CasinoID  uint64          `json:"casino_id"`
+	GameID    uint64          `json:"game_id"`
+	RequestID uint64          `json:"req_id"`
+	EventType int             `json:"event_type"`
+	Data      json.RawMessage `json:"data"`
+	BlockTime time.Time       `json:"block_time"`
+	// name of the source, empty for the default source
+	Source string `json:"source,omitempty"`
+	// time the monitor was notified about the event, nil for the events sent from the history
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+}
+
+// IsSynthetic reports whether the event is generated by the monitor, the synthetic events have no offset
+func (e *Event) IsSynthetic() bool {
+	return e.EventType < 0
+}
@@ -0,0 +1,33 @@
+package protocol
+
+import "encoding/json"
+
+type RequestMessage struct {
+	ID     *string         `json:"id"`
+	Method *string         `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type ResponseErrorMessage struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResponseMessage struct {
+	ID     *string               `json:"id"`
+	Result json.RawMessage       `json:"result"`
+	Error  *ResponseErrorMessage `json:"error"`
+}
+
+type EventMessage struct {
+	Offset uint64   `json:"offset"` // last event.offset
+	Events []*Event `json:"events"`
+	// synthetic events of the monitor, eg the stuck game alerts, they are out of the offset stream
+	Alerts []*Event `json:"alerts,omitempty"`
+}
+
+// CloseMessage is the text of the close frame sent to the sessions on shutdown
+type CloseMessage struct {
+	Reason string `json:"reason"`
+	Offset uint64 `json:"offset"` // last delivered event.offset
+}
@@ -0,0 +1,108 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"time"
+)
+
+// Platform event types, see configs/abi/events
+const (
+	EventGameStarted = iota
+	EventActionRequest
+	EventSignidicePart1Request
+	EventSignidicePart2Request
+	EventGameFinished
+	EventGameFailed
+	EventGameMessage
+)
+
+// Events generated by the monitor itself have negative types, they are not stored in the chain
+const (
+	EventAlertStuckGame = -1 - iota
+)
+
+// game_started.abi
+type GameStartedData struct{}
+
+// action_request.abi
+type ActionRequestData struct {
+	ActionType  uint8 `json:"action_type"`
+	NeedDeposit bool  `json:"need_deposit"`
+}
+
+// signidice_part_1_request.abi
+type SignidicePart1RequestData struct {
+	Digest eos.Checksum256 `json:"digest"`
+}
+
+// signidice_part_2_request.abi
+type SignidicePart2RequestData struct {
+	Digest eos.Checksum256 `json:"digest"`
+}
+
+// game_finished.abi
+type GameFinishedData struct {
+	PlayerWinAmount eos.Asset    `json:"player_win_amount"`
+	Msg             eos.HexBytes `json:"msg"`
+}
+
+// game_failed.abi
+type GameFailedData struct {
+	PlayerWinAmount eos.Asset    `json:"player_win_amount"`
+	Msg             eos.HexBytes `json:"msg"`
+}
+
+// game_message.abi
+type GameMessageData struct {
+	Msg eos.HexBytes `json:"msg"`
+}
+
+// alert_stuck_game topic, the game stays in the state longer than the configured timeout
+type StuckGameAlertData struct {
+	State        string    `json:"state"`
+	StateAt      time.Time `json:"state_at"`
+	StuckSeconds float64   `json:"stuck_seconds"`
+}
+
+func newEventData(eventType int) (interface{}, error) {
+	switch eventType {
+	case EventGameStarted:
+		return new(GameStartedData), nil
+	case EventActionRequest:
+		return new(ActionRequestData), nil
+	case EventSignidicePart1Request:
+		return new(SignidicePart1RequestData), nil
+	case EventSignidicePart2Request:
+		return new(SignidicePart2RequestData), nil
+	case EventGameFinished:
+		return new(GameFinishedData), nil
+	case EventGameFailed:
+		return new(GameFailedData), nil
+	case EventGameMessage:
+		return new(GameMessageData), nil
+	case EventAlertStuckGame:
+		return new(StuckGameAlertData), nil
+	}
+
+	return nil, fmt.Errorf("unknown event type: %d", eventType)
+}
+
+// Payload returns Data decoded to the struct of the event type, eg *GameFinishedData for EventGameFinished
+func (e *Event) Payload() (interface{}, error) {
+	data, err := newEventData(e.EventType)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e.Data) == 0 {
+		return data, nil
+	}
+
+	if err := json.Unmarshal(e.Data, data); err != nil {
+		return nil, fmt.Errorf("event type %d data error: %s", e.EventType, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,7 @@
+// Package shareddb embeds the migrations of the shared database
+package shareddb
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
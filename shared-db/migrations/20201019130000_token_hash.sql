@@ -0,0 +1,47 @@
+-- migrate:up
+-- the users keep their tokens, only the sha256 hash is stored
+ALTER TABLE monitor.users
+    ADD COLUMN token_hash character varying(64),
+    ADD COLUMN scopes     text[] not null default '{}',
+    ADD COLUMN expires_at timestamp with time zone,
+    ADD COLUMN revoked_at timestamp with time zone;
+
+UPDATE monitor.users SET token_hash = encode(digest(token, 'sha256'), 'hex');
+
+ALTER TABLE monitor.users ALTER COLUMN token_hash SET NOT NULL;
+
+DROP INDEX monitor.users_index_token;
+ALTER TABLE monitor.users DROP COLUMN token;
+
+CREATE UNIQUE INDEX users_index_token_hash ON monitor.users USING btree (token_hash);
+
+CREATE OR REPLACE FUNCTION monitor.create_user() RETURNS character varying(64) AS $$
+DECLARE
+    token character varying(64) := monitor.random_token();
+BEGIN
+    INSERT INTO monitor.users (token_hash, creation_date) VALUES (encode(digest(token, 'sha256'), 'hex'), now());
+    RETURN token;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+-- migrate:down
+-- the plain tokens can not be restored, the users need new tokens
+DROP FUNCTION monitor.create_user();
+
+CREATE OR REPLACE FUNCTION monitor.create_user() RETURNS character varying(64) AS $$
+    INSERT INTO monitor.users (token, creation_date) VALUES (monitor.random_token(), now()) returning token;
+$$ LANGUAGE SQL VOLATILE;
+
+DROP INDEX monitor.users_index_token_hash;
+
+ALTER TABLE monitor.users ADD COLUMN token character varying(64);
+UPDATE monitor.users SET token = token_hash;
+ALTER TABLE monitor.users ALTER COLUMN token SET NOT NULL;
+
+CREATE INDEX users_index_token ON monitor.users USING btree (token, id);
+
+ALTER TABLE monitor.users
+    DROP COLUMN token_hash,
+    DROP COLUMN scopes,
+    DROP COLUMN expires_at,
+    DROP COLUMN revoked_at;